@@ -0,0 +1,159 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// branch is the part of the branches API's response that we use
+type branch struct {
+	Commit struct {
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	} `json:"commit"`
+}
+
+// fetchBranchCommits fills in when the latest commit on every fork's default
+// branch was made. Unlike PushedAt, a push to any other branch doesn't count.
+// Forks without a default branch, e.g. empty ones, are left without a date.
+func fetchBranchCommits(ctx context.Context, baseURL, token string, repos []Repo) error {
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	sem := newReadSlots(ctx)
+
+	for i := range repos {
+		if repos[i].DefaultBranch == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *Repo) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+
+			u := repoURL(baseURL, r.Owner.Name, r.Name) + "/branches/" + url.PathEscape(r.DefaultBranch)
+			req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+			if err != nil {
+				return
+			}
+
+			var b branch
+			err = doRequest(req, token, &b)
+			if err != nil && err.Error() == ErrMsg404 {
+				return
+			}
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("fetching the default branch of %s: %w", repoKey(*r), err):
+				default:
+				}
+				return
+			}
+			r.branchCommittedAt = b.Commit.Commit.Committer.Date
+		}(&repos[i])
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	if len(errChan) > 0 {
+		return <-errChan
+	}
+	return nil
+}
+
+// branchPage is a page of a single branch. Its Link header's last page is the
+// number of branches, and without one the page holds all of them.
+type branchPage struct {
+	branches []json.RawMessage
+	lastPage int
+}
+
+func (p *branchPage) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &p.branches)
+}
+
+func (p *branchPage) readHeader(h http.Header) {
+	p.lastPage = lastPageFromLink(h.Get("Link"))
+}
+
+func (p *branchPage) count() int {
+	if p.lastPage > 0 {
+		return p.lastPage
+	}
+	return len(p.branches)
+}
+
+// lastPageFromLink returns the page number of the rel="last" link of a Link
+// header, or 0 if there's none
+func lastPageFromLink(link string) int {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok || !strings.Contains(params, `rel="last"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return 0
+		}
+		page, _ := strconv.Atoi(u.Query().Get("page"))
+		return page
+	}
+	return 0
+}
+
+// fetchBranchCounts fills in how many branches every fork has, requesting a
+// page of a single branch so that the number of pages is the number of branches
+func fetchBranchCounts(ctx context.Context, baseURL, token string, repos []Repo) error {
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	sem := newReadSlots(ctx)
+
+	for i := range repos {
+		wg.Add(1)
+		go func(r *Repo) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+
+			u := repoURL(baseURL, r.Owner.Name, r.Name) + "/branches?per_page=1"
+			req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+			if err != nil {
+				return
+			}
+
+			var p branchPage
+			err = doRequest(req, token, &p)
+			if err != nil && err.Error() == ErrMsg404 {
+				return
+			}
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("counting the branches of %s: %w", repoKey(*r), err):
+				default:
+				}
+				return
+			}
+			r.branchCount = p.count()
+		}(&repos[i])
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	if len(errChan) > 0 {
+		return <-errChan
+	}
+	return nil
+}
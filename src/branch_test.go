@@ -0,0 +1,162 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchBranchCommits(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Branch names with slashes are escaped into a single path segment
+			if r.URL.EscapedPath() == "/repos/test-owner/active-repo/branches/release%2Fv1" {
+				fmt.Fprintln(w, `{"commit": {"commit": {"committer": {"date": "2024-01-02T03:04:05Z"}}}}`)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("active-repo"), newTestRepo("empty-repo"), newTestRepo("no-branch-repo")}
+	repos[0].DefaultBranch, repos[1].DefaultBranch = "release/v1", "main"
+
+	if err := fetchBranchCommits(context.Background(), server.URL, "test-token", repos); err != nil {
+		t.Fatalf("fetchBranchCommits() failed: %v", err)
+	}
+
+	expected := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !repos[0].branchCommittedAt.Equal(expected) {
+		t.Errorf("Expected active-repo to be committed to at %s, got %s", expected, repos[0].branchCommittedAt)
+	}
+	if !repos[1].branchCommittedAt.IsZero() || !repos[2].branchCommittedAt.IsZero() {
+		t.Errorf("Expected the other repos to have no date, got %v", repos[1:])
+	}
+}
+
+func TestFetchBranchCommits_Error(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("forbidden-repo")}
+	repos[0].DefaultBranch = "main"
+
+	err := fetchBranchCommits(context.Background(), server.URL, "test-token", repos)
+	if err == nil || !strings.Contains(err.Error(), "test-owner/forbidden-repo") {
+		t.Errorf("Expected an error naming the repo, got %v", err)
+	}
+}
+
+func TestFilterForkedRepos_RecentBranch(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "active-repo", branchCommittedAt: time.Now()},
+		{Name: "stale-repo", branchCommittedAt: old},
+	}
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		maxAhead:      -1,
+		recentBranch:  true,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "stale-repo" {
+		t.Errorf("Expected only stale-repo to be unguarded, got %v", unguarded)
+	}
+	if reasons["active-repo"] != "default branch committed to today" {
+		t.Errorf("Unexpected reason %q", reasons["active-repo"])
+	}
+}
+
+func TestLastPageFromLink(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		link     string
+		expected int
+	}{
+		{"", 0},
+		{`<https://api.test/repositories/1/branches?per_page=1&page=2>; rel="next", ` +
+			`<https://api.test/repositories/1/branches?per_page=1&page=7>; rel="last"`, 7},
+		{`<https://api.test/repositories/1/branches?per_page=1&page=1>; rel="prev"`, 0},
+	}
+
+	for _, tt := range tests {
+		if got := lastPageFromLink(tt.link); got != tt.expected {
+			t.Errorf("lastPageFromLink(%q) = %d, want %d", tt.link, got, tt.expected)
+		}
+	}
+}
+
+func TestFetchBranchCounts(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/test-owner/busy-repo/branches":
+				w.Header().Set("Link",
+					`<https://api.test/repositories/1/branches?per_page=1&page=2>; rel="next", `+
+						`<https://api.test/repositories/1/branches?per_page=1&page=5>; rel="last"`)
+				w.Write([]byte(`[{"name": "main"}]`))
+			case "/repos/test-owner/plain-repo/branches":
+				w.Write([]byte(`[{"name": "main"}]`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("busy-repo"), newTestRepo("plain-repo"), newTestRepo("gone-repo")}
+	if err := fetchBranchCounts(context.Background(), server.URL, "test-token", repos); err != nil {
+		t.Fatalf("fetchBranchCounts() failed: %v", err)
+	}
+	if repos[0].branchCount != 5 || repos[1].branchCount != 1 || repos[2].branchCount != 0 {
+		t.Errorf("Expected 5, 1 and 0 branches, got %d, %d and %d",
+			repos[0].branchCount, repos[1].branchCount, repos[2].branchCount)
+	}
+}
+
+func TestFilterForkedRepos_BranchCount(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "busy-repo", branchCount: 4},
+		{Name: "plain-repo", branchCount: 1},
+	}
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		maxAhead:      -1,
+		maxBranches:   2,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "plain-repo" {
+		t.Errorf("Expected only plain-repo to be unguarded, got %v", unguarded)
+	}
+	if reasons["busy-repo"] != "protected: 4 branches" {
+		t.Errorf("Unexpected reason %q", reasons["busy-repo"])
+	}
+}
@@ -0,0 +1,91 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// cacheEntry is a response body along with the ETag GitHub sent for it
+type cacheEntry struct {
+	ETag string          `json:"etag"`
+	Body json.RawMessage `json:"body"`
+}
+
+// etagCache keeps the bodies of GET responses keyed by URL so that repeated
+// runs can send If-None-Match. Unchanged resources then come back as a 304,
+// which doesn't count against the rate limit. A nil cache is disabled.
+type etagCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	hits    int
+	dirty   bool
+}
+
+// loadETagCache reads the cache file, starting empty if it doesn't exist yet
+func loadETagCache(path string) (*etagCache, error) {
+	c := &etagCache{entries: map[string]cacheEntry{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *etagCache) get(url string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	return e, ok
+}
+
+func (c *etagCache) put(url string, e cacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = e
+	c.dirty = true
+}
+
+// hit records a response served from the cache
+func (c *etagCache) hit() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits++
+}
+
+// save writes the cache file if anything changed since it was loaded
+func (c *etagCache) save(path string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,121 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoadETagCache(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	cache, err := loadETagCache(filepath.Join(dir, "missing.json"))
+	if err != nil || len(cache.entries) != 0 {
+		t.Errorf("Expected an empty cache for a missing file, got %v, %v", cache, err)
+	}
+
+	path := filepath.Join(dir, "cache.json")
+	cache.put("https://api.test/user", cacheEntry{ETag: `"abc"`, Body: []byte(`{"login":"a"}`)})
+	if err := cache.save(path); err != nil {
+		t.Fatalf("save() failed: %v", err)
+	}
+
+	loaded, err := loadETagCache(path)
+	if err != nil {
+		t.Fatalf("loadETagCache() failed: %v", err)
+	}
+	e, ok := loaded.get("https://api.test/user")
+	if !ok || e.ETag != `"abc"` || string(e.Body) != `{"login":"a"}` {
+		t.Errorf("Expected the saved entry, got %+v", e)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadETagCache(path); err == nil || !strings.Contains(err.Error(), "invalid cache") {
+		t.Errorf("Expected an invalid cache error, got %v", err)
+	}
+}
+
+func TestDoRequest_ETag(t *testing.T) {
+	t.Parallel()
+	var notModified atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprintln(w, `{"login": "test-owner"}`)
+	}))
+	defer server.Close()
+
+	cache, _ := loadETagCache(filepath.Join(t.TempDir(), "cache.json"))
+	ctx := withRequestConfig(context.Background(), requestConfig{cache: cache})
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/user", nil)
+		var u user
+		if err := doRequest(req, "test-token", &u); err != nil {
+			t.Fatalf("doRequest() #%d failed: %v", i, err)
+		}
+		if u.Login != "test-owner" {
+			t.Errorf("Expected login test-owner on request #%d, got %q", i, u.Login)
+		}
+	}
+
+	if notModified.Load() != 1 || cache.hits != 1 {
+		t.Errorf("Expected the second request to be a cache hit, got %d 304s and %d hits",
+			notModified.Load(), cache.hits)
+	}
+}
+
+func TestCLI_CacheFile(t *testing.T) {
+	t.Parallel()
+	server, _ := newMockGitHubServer(t, nil)
+
+	// Tag every response of the mock API and answer revalidations with a 304
+	var notModified atomic.Int64
+	etagged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer etagged.Close()
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--cache-file", path}
+
+	for i := 0; i < 2; i++ {
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+
+		cliConfig := NewCLIConfig(
+			stdout,
+			stderr,
+			"test-version",
+		).withBaseURL(etagged.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		if exitCode := cliConfig.CLI(args); exitCode != 0 {
+			t.Fatalf("Expected exit code 0 on run #%d, got %d: %s", i, exitCode, stderr.String())
+		}
+	}
+
+	if notModified.Load() == 0 {
+		t.Error("Expected the second run to revalidate cached responses")
+	}
+}
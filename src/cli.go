@@ -1,38 +1,259 @@
 package src
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
 const (
 	// Exit codes
-	exitOk  = 0
-	exitErr = 1
+	exitOk          = 0
+	exitErr         = 1 // usage, argument and other errors
+	exitAuth        = 2 // the token was rejected or lacks permissions
+	exitPartial     = 3 // deletions or transfers failed after the run started
+	exitInterrupted = 4 // the run was interrupted by a signal
+	exitDrift       = 5 // a dry run asserted to be empty found forks to delete
 
 	// Error messages to catch from the GitHub API
 	ErrMsg401 = "API request failed with status: 401"
 	ErrMsg403 = "API request failed with status: 403"
 	ErrMsg404 = "API request failed with status: 404"
+
+	// Default headers sent with every API request
+	defaultAccept     = "application/vnd.github.v3+json"
+	defaultAPIVersion = "2022-11-28"
+
+	// Header carrying the ID the client generates for each operation, which stays
+	// the same across its retries
+	clientRequestIDHeader = "X-Client-Request-Id"
+
+	// Default GitHub API base URL
+	defaultBaseURL = "https://api.github.com"
+
+	// Default cap on the size of an API response body, far above a page of 100
+	// repos so that only a misbehaving endpoint hits it
+	defaultMaxResponseBytes = 32 << 20
+
+	// Default cap on how much of a failed response's body --verbose logs
+	defaultMaxBodyLogBytes = 4 << 10
+
+	// Default name of the page size query param, Gitea and Forgejo use "limit"
+	defaultPerPageParam = "per_page"
+
+	// Largest page GitHub serves, and the default so listings take the fewest requests
+	maxPerPage = 100
+
+	// Prefix of the environment variables that set flags, e.g. FORK_SWEEPER_OWNER
+	envPrefix = "FORK_SWEEPER_"
+
+	// Endpoints that list an owner's repos
+	endpointUsers = "users" // /users/{owner}/repos, public repos only
+	endpointUser  = "user"  // /user/repos, includes the token owner's private repos
+	endpointOrgs  = "orgs"  // /orgs/{owner}/repos, includes private repos visible to members
+
+	// Owner types picking the listing endpoint
+	ownerTypeUser = "user"
+	ownerTypeOrg  = "org"
+	ownerTypeAuto = "auto"
+
+	// Account types GitHub reports for users and organizations
+	userTypeUser = "User"
+	userTypeOrg  = "Organization"
+
+	// Visibilities the /user/repos listing can be scoped to
+	visibilityAll     = "all"
+	visibilityPublic  = "public"
+	visibilityPrivate = "private"
+
+	// Orders the listing endpoints can sort repos in, "" leaves it to the API
+	apiSortCreated   = "created"
+	apiSortUpdated   = "updated"
+	apiSortPushed    = "pushed"
+	apiSortFullName  = "full_name"
+	apiDirectionAsc  = "asc"
+	apiDirectionDesc = "desc"
+
+	// Activity modes for filterForkedRepos
+	activityModeAny = "any"
+	activityModeAll = "all"
+
+	// Timestamps that count towards a fork's age
+	ageBasisActivity = "activity" // created, updated and pushed
+	ageBasisChanges  = "changes"  // updated and pushed only
+
+	// Orders in which unguarded repos are deleted
+	deleteOrderOldest = "oldest"
+	deleteOrderNewest = "newest"
 )
 
-type repo struct {
-	Name   string `json:"name"`
-	URL    string `json:"html_url"`
-	IsFork bool   `json:"fork"`
-	Owner  struct {
+// Repo is a repository as returned by the GitHub API
+type Repo struct {
+	ID       int64  `json:"id"`        // stable across renames and transfers
+	FullName string `json:"full_name"` // owner/name
+	Name     string `json:"name"`
+	URL      string `json:"html_url"`
+	IsFork   bool   `json:"fork"`
+	Archived bool   `json:"archived"`
+	Owner    struct {
 		Name string `json:"login"`
 	} `json:"owner"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	PushedAt  time.Time `json:"pushed_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	PushedAt      time.Time   `json:"pushed_at"`
+	DefaultBranch string      `json:"default_branch,omitempty"`
+	Size          int         `json:"size"`              // in KB, 0 for repos without commits
+	OpenIssues    int         `json:"open_issues_count"` // includes open pull requests
+	Stars         int         `json:"stargazers_count"`
+	Parent        *repoParent `json:"parent,omitempty"`
+
+	// Set by fetchParents for forks without parent data
+	orphaned bool
+
+	// Set by fetchAheadCounts for forks compared against their parent
+	aheadBy         int
+	othersCommitted bool // someone but the owner authored commits ahead of the parent
+	compared        bool
+
+	// Set by fetchMarkers for forks containing the marker file
+	marked bool
+
+	// Set by fetchBranchCommits to the date of the default branch's latest commit
+	branchCommittedAt time.Time
+
+	// Set by fetchBranchCounts to the number of branches
+	branchCount int
+}
+
+// pristine reports whether the fork has no commits of its own: it's either
+// empty or, when compared against its parent, not ahead of it
+func (r Repo) pristine() bool {
+	return r.Size == 0 || (r.compared && r.aheadBy == 0)
+}
+
+// lastActivity returns the most recent of the repo's created, updated and pushed timestamps
+func (r Repo) lastActivity() time.Time {
+	last := r.CreatedAt
+	if r.UpdatedAt.After(last) {
+		last = r.UpdatedAt
+	}
+	if r.PushedAt.After(last) {
+		last = r.PushedAt
+	}
+	return last
+}
+
+// firstActivity returns the oldest of the repo's created, updated and pushed timestamps
+func (r Repo) firstActivity() time.Time {
+	first := r.CreatedAt
+	if r.UpdatedAt.Before(first) {
+		first = r.UpdatedAt
+	}
+	if r.PushedAt.Before(first) {
+		first = r.PushedAt
+	}
+	return first
+}
+
+// withoutCreated returns a copy of the repo whose created timestamp no longer counts
+// as activity, so only updates and pushes decide how old the fork is
+func (r Repo) withoutCreated() Repo {
+	r.CreatedAt = r.UpdatedAt
+	if r.PushedAt.Before(r.CreatedAt) {
+		r.CreatedAt = r.PushedAt
+	}
+	return r
+}
+
+type user struct {
+	Login       string `json:"login"`
+	Type        string `json:"type"`
+	PublicRepos int    `json:"public_repos"`
+
+	// Only returned for the authenticated user
+	PrivateRepos *int `json:"total_private_repos,omitempty"`
+
+	// Scopes the token was granted, nil when the response didn't report them
+	scopes []string
+}
+
+// readHeader records the OAuth scopes that classic tokens report with every response
+func (u *user) readHeader(h http.Header) {
+	values := h.Values("X-OAuth-Scopes")
+	if values == nil {
+		return
+	}
+	u.scopes = []string{}
+	for _, v := range values {
+		for _, scope := range strings.Split(v, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				u.scopes = append(u.scopes, scope)
+			}
+		}
+	}
+}
+
+// missingScope reports whether the token is known to lack the scope. Fine-grained
+// tokens don't report scopes, so they're never known to lack one.
+func (u user) missingScope(scope string) bool {
+	return u.scopes != nil && !slices.Contains(u.scopes, scope)
+}
+
+// impliedScopes lists the broader scopes that grant each scope, since GitHub
+// only reports the scopes a token was granted, not the ones they include
+var impliedScopes = map[string][]string{
+	"public_repo":     {"repo"},
+	"repo:status":     {"repo"},
+	"repo_deployment": {"repo"},
+	"repo:invite":     {"repo"},
+	"security_events": {"repo"},
+	"read:org":        {"write:org", "admin:org"},
+	"write:org":       {"admin:org"},
+	"read:user":       {"user"},
+	"user:email":      {"user"},
+	"user:follow":     {"user"},
+	"read:packages":   {"write:packages"},
+}
+
+// missingScopes returns the required scopes the token wasn't granted, directly
+// or through a broader scope, in the order they were required
+func (u user) missingScopes(required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		granted := slices.Contains(u.scopes, scope)
+		for _, broader := range impliedScopes[scope] {
+			granted = granted || slices.Contains(u.scopes, broader)
+		}
+		if !granted {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// repoCounts describes how many repos the user has, as context for how
+// aggressively to clean up
+func (u user) repoCounts() string {
+	if u.PrivateRepos == nil {
+		return fmt.Sprintf("%s has %d public repos", u.Login, u.PublicRepos)
+	}
+	return fmt.Sprintf(
+		"%s has %d public and %d private repos", u.Login, u.PublicRepos, *u.PrivateRepos)
 }
 
 var httpClientPool = sync.Pool{
@@ -41,142 +262,715 @@ var httpClientPool = sync.Pool{
 	},
 }
 
+// forkListURL builds the URL of a page of the owner's repos on the given endpoint.
+// The /user/repos endpoint doesn't accept type=forks alongside affiliation, so forks
+// are filtered client-side there, as they are when the host doesn't support the param.
+func forkListURL(baseURL, owner, endpoint string, pageNum, perPage int, cfg requestConfig) string {
+	query := fmt.Sprintf("page=%d&%s=%d", pageNum, cfg.perPageParam, perPage)
+	if !cfg.omitTypeParam && !cfg.includeSources {
+		query = "type=forks&" + query
+	}
+
+	// Pinning the order so that pages don't shift between requests
+	var order string
+	if cfg.sort != "" {
+		order += "&sort=" + cfg.sort
+	}
+	if cfg.direction != "" {
+		order += "&direction=" + cfg.direction
+	}
+
+	switch endpoint {
+	case endpointUser:
+		// GitHub rejects type along with affiliation or visibility, so forks are
+		// filtered client-side
+		u := fmt.Sprintf(
+			"%s/user/repos?affiliation=owner&page=%d&%s=%d",
+			baseURL,
+			pageNum,
+			cfg.perPageParam,
+			perPage)
+		if cfg.visibility != "" && cfg.visibility != visibilityAll {
+			u += "&visibility=" + cfg.visibility
+		}
+		return u + order
+	case endpointOrgs:
+		return fmt.Sprintf("%s/orgs/%s/repos?%s%s", baseURL, owner, query, order)
+	default:
+		return fmt.Sprintf("%s/users/%s/repos?%s%s", baseURL, owner, query, order)
+	}
+}
+
+// listingPath is the path of the endpoint forks are listed from, for diagnostics
+func listingPath(owner, endpoint string, searching bool) string {
+	switch {
+	case searching:
+		return "/search/repositories"
+	case endpoint == endpointUser:
+		return "/user/repos"
+	case endpoint == endpointOrgs:
+		return fmt.Sprintf("/orgs/%s/repos", owner)
+	default:
+		return fmt.Sprintf("/users/%s/repos", owner)
+	}
+}
+
+// repoURL is the API URL of a repo under baseURL, which may carry a path prefix
+// like /api/v3 on GitHub Enterprise Server or /api/v1 on Gitea
+func repoURL(baseURL, owner, name string) string {
+	return fmt.Sprintf("%s/repos/%s/%s", strings.TrimSuffix(baseURL, "/"), owner, name)
+}
+
+// fetchForkedReposPage fetches a page of the owner's forks. It also returns how
+// many repos the API listed on the page before non-forks were dropped, which
+// tells whether the listing goes on.
 func fetchForkedReposPage(
 	ctx context.Context,
 	baseURL,
 	owner,
-	token string,
+	token,
+	endpoint string,
 	pageNum,
-	perPage int) ([]repo, error) {
+	perPage int) ([]Repo, int, error) {
 
-	url := fmt.Sprintf(
-		"%s/users/%s/repos?type=forks&page=%d&per_page=%d",
-		baseURL,
-		owner,
-		pageNum,
-		perPage)
+	url := forkListURL(baseURL, owner, endpoint, pageNum, perPage, requestConfigFrom(ctx))
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	var repos []repo
+	var repos []Repo
 	if err := doRequest(req, token, &repos); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if requestConfigFrom(ctx).includeSources {
+		return repos, len(repos), nil
+	}
+
+	// Trusting type=forks on hosts that don't populate the fork field, the repos
+	// are marked as forks for the checks that look at it later
+	if requestConfigFrom(ctx).noForkFilter {
+		for i := range repos {
+			repos[i].IsFork = true
+		}
+		return repos, len(repos), nil
 	}
 
 	// Filter out non-forked repositories
-	var forkedRepos []repo
+	var forkedRepos []Repo
 	for _, r := range repos {
 		if r.IsFork {
 			forkedRepos = append(forkedRepos, r)
 		}
 	}
-	return forkedRepos, nil
+	return forkedRepos, len(repos), nil
 }
 
 func fetchForkedRepos(
 	ctx context.Context,
 	baseURL,
 	owner,
-	token string,
+	token,
+	endpoint string,
 	perPage,
-	maxPage int) ([]repo, error) {
-
-	var allRepos []repo
-	for pageNum := 1; pageNum <= maxPage; pageNum++ {
-		repos, err := fetchForkedReposPage(
-			ctx,     // ctx
-			baseURL, // baseURL
-			owner,   // owner
-			token,   // token
-			pageNum, // pageNum
-			perPage, // perPage
+	maxPage int) ([]Repo, error) {
+
+	cfg := requestConfigFrom(ctx)
+	pages := newPageCollector(cfg.startPage)
+	failedInARow := 0
+	for pageNum := cfg.startPage; pageNum <= maxPage; pageNum++ {
+		repos, listed, err := fetchForkedReposPage(
+			ctx,      // ctx
+			baseURL,  // baseURL
+			owner,    // owner
+			token,    // token
+			endpoint, // endpoint
+			pageNum,  // pageNum
+			perPage,  // perPage
 		)
 
+		// In best-effort mode a failed page is skipped unless the whole listing is
+		// bound to fail, e.g. the owner doesn't exist or the run was interrupted
+		if err != nil && bestEffortSkippable(ctx, err) {
+			pages.fail(pageNum, err)
+			if failedInARow++; failedInARow == maxFailedPagesInARow {
+				break
+			}
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		if len(repos) == 0 {
+		// A page can hold no forks at all when non-forks are filtered client-side,
+		// so the listing ends on the API's count instead. A short page is the
+		// last one on GitHub, other hosts may serve fewer repos than asked for.
+		failedInARow = 0
+		pages.add(pageNum, repos)
+		if listed == 0 || (listed < perPage && cfg.perPageParam == defaultPerPageParam) {
 			break
 		}
+	}
+	return pages.repos(), pages.incomplete()
+}
+
+// maxFailedPagesInARow ends a best-effort listing that keeps failing, since
+// there's no telling where it ends otherwise
+const maxFailedPagesInARow = 3
+
+// bestEffortSkippable reports whether a best-effort listing can skip past a page
+// that failed with err
+func bestEffortSkippable(ctx context.Context, err error) bool {
+	return requestConfigFrom(ctx).bestEffort &&
+		ctx.Err() == nil &&
+		err.Error() != ErrMsg401 &&
+		err.Error() != ErrMsg404
+}
+
+// requestConfig holds the per-run settings that doRequest applies to every API
+// request. It travels on the request context so that the fetch and delete
+// helpers don't need to thread it through their signatures.
+type requestConfig struct {
+	accept     string
+	apiVersion string
+	retries    int           // per-request retries of transient failures
+	retryDelay time.Duration // delay before the first retry
+	budget     *retryBudget  // retries left across the whole run
+
+	// Fails requests refused by a rate limit with a rateLimitError instead of
+	// retrying them after the wait GitHub asks for
+	abortOnRateLimit bool
+
+	maxResponseBytes int64      // cap on the size of a response body
+	cache            *etagCache // conditional GETs when set
+
+	// Logs the body of failed responses when set, truncated to maxBodyLogBytes
+	errorBodyLog    io.Writer
+	maxBodyLogBytes int64
+
+	// Logs every successful request with its status and request ID when set
+	requestLog io.Writer
+
+	// Listing dialect of GitHub compatible hosts like Gitea and Forgejo
+	perPageParam  string // name of the page size query param
+	omitTypeParam bool   // don't send type=forks, filter forks client-side only
+	noForkFilter  bool   // rely on type=forks only, for hosts without a fork field
+
+	// Lists source repos along with forks
+	includeSources bool
+
+	// Scopes the /user/repos listing to public or private repos server-side
+	visibility string
+
+	// Sort order of the listing, the API's default when empty
+	sort      string
+	direction string
+
+	// First page of the listing to fetch, for scanning a window of pages
+	startPage int
+
+	// Skips listing pages that fail, returning an incompleteListingError along
+	// with the rest
+	bestEffort bool
+
+	// Concurrent requests of the per-fork parent and comparison checks, scaled
+	// to the rate limit headroom up to readConcurrency when autoConcurrency is set
+	readConcurrency int
+	autoConcurrency *concurrencyController
+
+	// Cancels the remaining deletions on the first failure
+	failFast bool
+
+	// Tracks how much of the rate limit the run used when set
+	quota *quotaTracker
+
+	// Throttles DELETE requests to a maximum rate
+	deleteLimiter *rateLimiter
+
+	// Staggers the start of concurrent deletions to avoid a burst
+	deleteJitter *jitter
+
+	// Fraction of deletes that fail without a request, for testing against a
+	// local server
+	simulateFailureRate float64
+}
+
+type requestConfigKey struct{}
+
+func withRequestConfig(ctx context.Context, cfg requestConfig) context.Context {
+	return context.WithValue(ctx, requestConfigKey{}, cfg)
+}
+
+func requestConfigFrom(ctx context.Context) requestConfig {
+	cfg, _ := ctx.Value(requestConfigKey{}).(requestConfig)
+	if cfg.accept == "" {
+		cfg.accept = defaultAccept
+	}
+	if cfg.apiVersion == "" {
+		cfg.apiVersion = defaultAPIVersion
+	}
+	if cfg.retryDelay == 0 {
+		cfg.retryDelay = defaultRetryDelay
+	}
+	if cfg.perPageParam == "" {
+		cfg.perPageParam = defaultPerPageParam
+	}
+	if cfg.maxResponseBytes == 0 {
+		cfg.maxResponseBytes = defaultMaxResponseBytes
+	}
+	if cfg.maxBodyLogBytes == 0 {
+		cfg.maxBodyLogBytes = defaultMaxBodyLogBytes
+	}
+	if cfg.readConcurrency == 0 {
+		cfg.readConcurrency = defaultReadConcurrency
+	}
+	if cfg.startPage == 0 {
+		cfg.startPage = 1
+	}
+	return cfg
+}
+
+// fetchAuthenticatedUser returns the user the token belongs to
+func fetchAuthenticatedUser(ctx context.Context, baseURL, token string) (user, error) {
+	var u user
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/user", nil)
+	if err != nil {
+		return u, err
+	}
+
+	err = doRequest(req, token, &u)
+	return u, err
+}
+
+func fetchUser(ctx context.Context, baseURL, owner, token string) (user, error) {
+	var u user
+
+	url := fmt.Sprintf("%s/users/%s", baseURL, owner)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return u, err
+	}
+
+	err = doRequest(req, token, &u)
+	return u, err
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+// checkHealth verifies that the API is reachable, the token is valid and the
+// owner exists, reporting each step to stdout
+func checkHealth(ctx context.Context, stdout io.Writer, baseURL, owner, token string) error {
+	authUser, err := fetchAuthenticatedUser(ctx, baseURL, token)
+	if err != nil {
+		if err.Error() == ErrMsg401 {
+			return errInvalidToken
+		}
+		return err
+	}
+	fmt.Fprintf(stdout, "    - token: ok (authenticated as %s)\n", authUser.Login)
+
+	if _, err := fetchUser(ctx, baseURL, owner, token); err != nil {
+		if err.Error() == ErrMsg404 {
+			return fmt.Errorf("user not found")
+		}
+		return err
+	}
+	fmt.Fprintf(stdout, "    - owner: ok (%s exists)\n", owner)
+	return nil
+}
+
+// logErrorBody writes the body of a failed response to the error log, truncated so
+// that a huge or hostile body can't flood the terminal
+func logErrorBody(cfg requestConfig, req *http.Request, resp *http.Response) {
+	if cfg.errorBodyLog == nil {
+		return
+	}
 
-		allRepos = append(allRepos, repos...)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, cfg.maxBodyLogBytes+1))
+	if err != nil {
+		return
+	}
+	var truncated string
+	if int64(len(body)) > cfg.maxBodyLogBytes {
+		body, truncated = body[:cfg.maxBodyLogBytes], "...(truncated)"
+	}
+	fmt.Fprintf(
+		cfg.errorBodyLog,
+		"%s %s failed with status %d%s: %s%s\n",
+		req.Method,
+		req.URL.Redacted(),
+		resp.StatusCode,
+		formatRequestID(req, resp),
+		bytes.TrimSpace(body),
+		truncated)
+}
+
+// logRequest logs a successful request along with its request ID when debugging
+func logRequest(cfg requestConfig, req *http.Request, resp *http.Response) {
+	if cfg.requestLog == nil {
+		return
+	}
+	fmt.Fprintf(
+		cfg.requestLog,
+		"%s %s returned status %d%s\n",
+		req.Method,
+		req.URL.Redacted(),
+		resp.StatusCode,
+		formatRequestID(req, resp))
+}
+
+// logRetry logs a failed attempt that's about to be retried when debugging, so the
+// attempts of an operation can be matched up by their client request ID
+func logRetry(cfg requestConfig, req *http.Request, resp *http.Response, err error, attempt int) {
+	if cfg.requestLog == nil {
+		return
 	}
-	return allRepos, nil
+	outcome := fmt.Sprintf("failed: %s", err)
+	if resp != nil {
+		outcome = fmt.Sprintf("returned status %d", resp.StatusCode)
+	}
+	fmt.Fprintf(
+		cfg.requestLog,
+		"%s %s attempt %d %s, retrying (client request ID %s)\n",
+		req.Method,
+		req.URL.Redacted(),
+		attempt,
+		outcome,
+		req.Header.Get(clientRequestIDHeader))
+}
+
+// formatRequestID describes the X-GitHub-Request-Id of a response, which GitHub
+// support can use to look the request up, and the client request ID it was sent
+// with, or is empty if there are neither
+func formatRequestID(req *http.Request, resp *http.Response) string {
+	var ids []string
+	if id := resp.Header.Get("X-GitHub-Request-Id"); id != "" {
+		ids = append(ids, "request ID "+id)
+	}
+	if id := req.Header.Get(clientRequestIDHeader); id != "" {
+		ids = append(ids, "client request ID "+id)
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(ids, ", "))
+}
+
+// newClientRequestID returns a random version 4 UUID identifying an operation
+func newClientRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// errEmptyBody is returned when a 200 OK response that should carry JSON is empty,
+// e.g. when a proxy swallows the body, instead of decoding it into a zero result
+var errEmptyBody = errors.New("API response has an empty body")
+
+// headerReader is implemented by results that also need the response headers
+type headerReader interface {
+	readHeader(h http.Header)
 }
 
 func doRequest(req *http.Request, token string, result any) error {
 	httpClient := httpClientPool.Get().(*http.Client)
 	defer httpClientPool.Put(httpClient)
 
+	cfg := requestConfigFrom(req.Context())
+
 	req.Header.Add("Authorization", "Bearer "+token)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
+	req.Header.Add("Accept", cfg.accept)
 	req.Header.Add("User-Agent", "Mozilla/5.0")
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Add("X-GitHub-Api-Version", cfg.apiVersion)
+
+	// Tagging the operation once so that its retries carry the same ID, unless
+	// the caller already did
+	if req.Header.Get(clientRequestIDHeader) == "" {
+		req.Header.Set(clientRequestIDHeader, newClientRequestID())
+	}
+
+	// Revalidating cached GET responses, a 304 reuses the cached body
+	cacheKey := req.URL.String()
+	cached, isCached := cfg.cache.get(cacheKey)
+	if isCached && req.Method == http.MethodGet {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
 
-	resp, err := httpClient.Do(req)
+	// Retrying transient failures with exponential backoff while the request's
+	// retries and the run's retry budget last
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = httpClient.Do(req)
+		cfg.quota.observe(resp)
+		cfg.autoConcurrency.observe(resp)
+		if attempt >= cfg.retries ||
+			!shouldRetry(req.Context(), resp, err) ||
+			(cfg.abortOnRateLimit && isRateLimited(resp)) ||
+			!cfg.budget.take() {
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		logRetry(cfg, req, resp, err, attempt+1)
+		if err := sleepContext(req.Context(), retryDelay(resp, backoffDelay(cfg.retryDelay, attempt))); err != nil {
+			return err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return err
+			}
+		}
+	}
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= http.StatusBadRequest {
+		rateLimited := cfg.abortOnRateLimit && isRateLimited(resp)
+		secondaryRateLimit := isSecondaryRateLimit(resp)
+		var verr *validationError
+		if resp.StatusCode == http.StatusUnprocessableEntity {
+			verr = readValidationError(resp)
+		}
+		logErrorBody(cfg, req, resp)
+		if rateLimited {
+			return newRateLimitError(resp)
+		}
+		if secondaryRateLimit {
+			return errSecondaryRateLimit
+		}
+		if verr != nil {
+			return verr
+		}
 		return fmt.Errorf("API request failed with status: %d", resp.StatusCode)
 	}
+	logRequest(cfg, req, resp)
+
+	if hr, ok := result.(headerReader); ok {
+		hr.readHeader(resp.Header)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		cfg.cache.hit()
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(cached.Body, result)
+	}
+
+	// Buffering the body of cacheable responses to store it along with the ETag
+	etag := resp.Header.Get("ETag")
+	if cfg.cache != nil && req.Method == http.MethodGet && etag != "" && result != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, resp.Body, cfg.maxResponseBytes))
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("API response exceeded %d bytes", maxBytesErr.Limit)
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, result); err != nil {
+			return err
+		}
+		cfg.cache.put(cacheKey, cacheEntry{ETag: etag, Body: body})
+		return nil
+	}
+
+	// Responses like 204 No Content or an empty 202 Accepted have nothing to decode.
+	// The body is decoded as it streams in, up to the configured size.
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		body := http.MaxBytesReader(nil, resp.Body, cfg.maxResponseBytes)
+		err := json.NewDecoder(body).Decode(result)
 
-	if result != nil {
-		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("API response exceeded %d bytes", maxBytesErr.Limit)
+		}
+		if errors.Is(err, io.EOF) && resp.StatusCode == http.StatusOK {
+			return errEmptyBody
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
 			return err
 		}
 	}
 	return nil
 }
 
-// filterForkedRepos filters forked repositories based on their update date and whether their name matches any in the protectedRepos list using a basic form of fuzzy matching.
-func filterForkedRepos(
-	forkedRepos []repo,
-	guardedRepoNames []string,
-	olderThanDays int) ([]repo, []repo) {
-
-	unguardedRepos, guardedRepos := []repo{}, []repo{}
+// filterOptions configures how filterForkedRepos splits repos into unguarded and guarded
+type filterOptions struct {
+	guardRules      []guardRule
+	guardFullName   bool // match guard rules against owner/name instead of the name
+	olderThanDays   int
+	activityMode    string
+	ageBasis        string
+	onlyArchived    bool // guard forks that aren't archived
+	excludeArchived bool // guard forks that are archived
+	protectOrphans  bool // guard forks whose upstream is gone
+	deleteOrphans   bool // select forks whose upstream is gone regardless of age
+	maxAhead        int  // guard compared forks more commits ahead of their parent, -1 disables
+	keepLatest      int  // guard the n most recently active forks regardless of age
+	minIdleDays     int  // judge age by the pushed timestamp alone when positive
+	emptyOnly       bool // guard forks with commits of their own, or unknown ones
+	protectOthers   bool // guard compared forks with commits authored by others
+	protectIssues   bool // guard forks with open issues or pull requests
+	minStars        int  // guard forks with fewer stars, -1 disables
+	maxStars        int  // guard forks with more stars, -1 disables
+	recentBranch    bool // guard forks whose default branch has commits within the cutoff
+	maxBranches     int  // guard forks with more branches, -1 disables
+
+	// pushedWindow, when set, guards forks last pushed outside of it
+	pushedWindow pushWindow
+
+	// parentOwner, when set, guards forks of repos owned by anyone else
+	parentOwner string
+
+	// includeSources guards the source repos listed along with forks
+	includeSources bool
+
+	// markerFile, when set, guards forks marked as containing this file
+	markerFile string
+
+	// excludeName, when set, drops forks whose name matches from both results
+	excludeName *regexp.Regexp
+
+	// onDecision, when set, is called for every repo as soon as it is classified
+	onDecision func(r Repo, guarded bool, reason string)
+}
 
+// filterForkedRepos filters forked repositories based on their last activity and whether their name matches any of the guard rules.
+// A repo's last activity is the most recent of its created, updated and pushed timestamps,
+// so "older than n days" means no activity of any kind in n days. In "all" activity mode
+// the oldest timestamp governs instead, so every timestamp must be recent to guard a repo.
+// With the "changes" age basis the created timestamp is ignored, so a fork created
+// recently but never touched since isn't kept by its creation alone. A positive
+// minIdleDays replaces all of this with the pushed timestamp alone. Every check is
+// one of the GuardFuncs of builtinGuards, the same ones Filter's callers get.
+func filterForkedRepos(forkedRepos []Repo, opts filterOptions) ([]Repo, []Repo) {
 	now := time.Now()
 
-	// Convert olderThanDays to duration and subtract from current time to get cutoff date
-	cutOffDate := now.Add(time.Duration(-olderThanDays) * 24 * time.Hour)
+	// Finding the most recently active forks to keep regardless of age
+	repos := slices.DeleteFunc(slices.Clone(forkedRepos), func(r Repo) bool {
+		return opts.excludeName != nil && opts.excludeName.MatchString(r.Name)
+	})
+	latest := make(map[string]bool, opts.keepLatest)
+	if opts.keepLatest > 0 {
+		candidates := slices.Clone(repos)
+		sortReposByActivity(candidates, deleteOrderNewest)
+		for _, r := range candidates[:min(opts.keepLatest, len(candidates))] {
+			latest[repoKey(r)] = true
+		}
+	}
 
-	for _, repo := range forkedRepos {
-		// Check if repo activity is after cutoff date or name matches guarded list
-		hasRecentActivity := repo.PushedAt.After(cutOffDate) ||
-			repo.UpdatedAt.After(cutOffDate) || repo.CreatedAt.After(cutOffDate)
+	// Unguarded repos are deleted for their age, or for being orphaned
+	active := activityCheck(opts, now)
+	deleteReason := func(r Repo) string {
+		if opts.deleteOrphans && r.orphaned {
+			return "orphaned fork"
+		}
+		_, reason := active(r)
+		return reason
+	}
+	return splitByGuards(repos, builtinGuards(opts, now, latest), deleteReason, opts.onDecision)
+}
 
-		isGuardedName := false
-		for _, name := range guardedRepoNames {
-			repoName := strings.ToLower(repo.Name)
-			name = strings.ToLower(name)
+// formatOpenIssues describes how many issues, including pull requests, are open
+// on the repo
+func formatOpenIssues(r Repo) string {
+	if r.OpenIssues == 1 {
+		return "1 open issue"
+	}
+	return fmt.Sprintf("%d open issues", r.OpenIssues)
+}
 
-			if strings.TrimSpace(name) != "" && strings.Contains(repoName, name) {
-				isGuardedName = true
-				break
-			}
-		}
+// formatStars describes how many stars the repo has
+func formatStars(r Repo) string {
+	if r.Stars == 1 {
+		return "1 star"
+	}
+	return fmt.Sprintf("%d stars", r.Stars)
+}
 
-		if hasRecentActivity || isGuardedName {
-			guardedRepos = append(guardedRepos, repo)
-		} else {
-			unguardedRepos = append(unguardedRepos, repo)
+// formatAge renders how long before now t was, in whole days
+func formatAge(now, t time.Time) string {
+	days := int(now.Sub(t).Hours() / 24)
+	if days <= 0 {
+		return "today"
+	}
+	return fmt.Sprintf("%dd ago", days)
+}
+
+// limitPerOwner keeps at most n repos of each owner in listing order and counts
+// how many of each owner's repos were skipped. Owners are compared
+// case-insensitively and named as in their first repo.
+func limitPerOwner(repos []Repo, n int) ([]Repo, map[string]int) {
+	kept := make([]Repo, 0, len(repos))
+	names := map[string]string{}
+	counts := map[string]int{}
+	skipped := map[string]int{}
+	for _, r := range repos {
+		owner := strings.ToLower(r.Owner.Name)
+		if _, ok := names[owner]; !ok {
+			names[owner] = r.Owner.Name
+		}
+		if counts[owner] >= n {
+			skipped[names[owner]]++
+			continue
 		}
+		counts[owner]++
+		kept = append(kept, r)
+	}
+	return kept, skipped
+}
+
+// truncateList keeps the first n repos for display and counts the ones left out.
+// An n of 0 keeps them all.
+func truncateList(repos []Repo, n int) ([]Repo, int) {
+	if n <= 0 || len(repos) <= n {
+		return repos, 0
+	}
+	return repos[:n], len(repos) - n
+}
+
+// writeMore writes the footer of a list that truncateList left more repos out of
+func writeMore(w io.Writer, more int) {
+	if more > 0 {
+		fmt.Fprintf(w, "    ... and %d more\n", more)
 	}
+}
 
-	return unguardedRepos, guardedRepos
+// sortReposByActivity sorts repos in place by their last activity, either oldest or
+// newest first. Repos with the same last activity keep their listing order.
+func sortReposByActivity(repos []Repo, order string) {
+	slices.SortStableFunc(repos, func(a, b Repo) int {
+		if order == deleteOrderNewest {
+			return b.lastActivity().Compare(a.lastActivity())
+		}
+		return a.lastActivity().Compare(b.lastActivity())
+	})
 }
 
 func deleteRepo(ctx context.Context, baseURL, owner, name, token string) error {
-	url := fmt.Sprintf("%s/repos/%s/%s", baseURL, owner, name)
+	url := repoURL(baseURL, owner, name)
+
+	cfg := requestConfigFrom(ctx)
+	if err := cfg.deleteLimiter.wait(ctx); err != nil {
+		return err
+	}
+
+	rate := cfg.simulateFailureRate
+	if rate > 0 && simulatedFailure(rate, owner+"/"+name) {
+		return fmt.Errorf("simulated failure deleting %s/%s", owner, name)
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
@@ -186,54 +980,235 @@ func deleteRepo(ctx context.Context, baseURL, owner, name, token string) error {
 	return doRequest(req, token, nil)
 }
 
-func deleteRepos(ctx context.Context, baseURL, token string, repos []repo) error {
+// deleteTiming records how long the DELETE request for a repo took
+type deleteTiming struct {
+	repo     Repo
+	duration time.Duration
+	err      error
+}
+
+// exitCodeFor picks the exit code of a failed run: interrupted when a signal
+// cancelled it, auth when the API rejected the token and a generic error otherwise
+func exitCodeFor(ctx context.Context, err error) int {
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case errors.Is(err, errInvalidToken),
+		strings.Contains(err.Error(), ErrMsg401),
+		strings.Contains(err.Error(), ErrMsg403):
+		return exitAuth
+	default:
+		return exitErr
+	}
+}
+
+// deleteExitCode picks the exit code of a run whose deletions or transfers failed
+// after succeeded of them went through. It's an auth error only if the token
+// couldn't delete anything.
+func deleteExitCode(ctx context.Context, err error, succeeded int) int {
+	code := exitCodeFor(ctx, err)
+	if code == exitErr || (code == exitAuth && succeeded > 0) {
+		return exitPartial
+	}
+	return code
+}
+
+func deleteRepos(
+	ctx context.Context,
+	baseURL,
+	token string,
+	repos []Repo) (deleteResult, error) {
+
 	var wg sync.WaitGroup
 	errChan := make(chan error, 1)
+	timings := make([]deleteTiming, len(repos))
+	delays := requestConfigFrom(ctx).deleteJitter
 
-	for _, r := range repos {
+	// In fail-fast mode the first failure cancels the deletions still to come
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	failFast := requestConfigFrom(ctx).failFast
+
+	for i, r := range repos {
 		wg.Add(1)
-		go func(r repo) {
+		go func(i int, r Repo) {
 			defer wg.Done()
-			if err := deleteRepo(ctx, baseURL, r.Owner.Name, r.Name, token); err != nil {
+
+			// Staggering the first requests so they don't all land at once
+			err := sleepContext(runCtx, delays.next())
+			start := time.Now()
+			if err == nil {
+				err = deleteRepo(runCtx, baseURL, r.Owner.Name, r.Name, token)
+			}
+			if err != nil && runCtx.Err() != nil && ctx.Err() == nil {
+				err = errDeleteCancelled
+			}
+			timings[i] = deleteTiming{repo: r, duration: time.Since(start), err: err}
+
+			if err != nil && err != errDeleteCancelled {
 				select {
 				case errChan <- err:
 				default:
 				}
+				if failFast {
+					cancel()
+				}
 			}
-		}(r)
+		}(i, r)
 	}
 
 	wg.Wait()
 	close(errChan)
 
+	result := newDeleteResult(timings)
 	if len(errChan) > 0 {
-		return <-errChan
+		return result, <-errChan
 	}
-	return nil
+	return result, nil
 }
 
-type cliConfig struct {
-	// Required
-	stdout  io.Writer
-	stderr  io.Writer
+// deleteResult is the outcome of deleteRepos: the repos that were deleted and
+// the ones that failed, both in their original order, along with the timing of
+// every deletion
+type deleteResult struct {
+	succeeded []Repo
+	failed    []repoError
+	timings   []deleteTiming
+}
+
+// repoError is a repo whose deletion, or other operation, failed
+type repoError struct {
+	repo Repo
+	err  error
+}
+
+func newDeleteResult(timings []deleteTiming) deleteResult {
+	result := deleteResult{timings: timings}
+	for _, t := range timings {
+		if t.err != nil {
+			result.failed = append(result.failed, repoError{repo: t.repo, err: t.err})
+		} else {
+			result.succeeded = append(result.succeeded, t.repo)
+		}
+	}
+	return result
+}
+
+// cancelled counts the failed deletions that fail-fast mode cancelled
+func (r deleteResult) cancelled() int {
+	var n int
+	for _, f := range r.failed {
+		if f.err == errDeleteCancelled {
+			n++
+		}
+	}
+	return n
+}
+
+// errDeleteCancelled marks the deletions that fail-fast mode cancelled after
+// another one failed
+var errDeleteCancelled = errors.New("cancelled after an earlier failure")
+
+// printFailedDeletions lists the repos whose deletion failed along with why,
+// leaving out the ones cancelled after another failure
+func printFailedDeletions(w io.Writer, failed []repoError) {
+	var lines []string
+	for _, f := range failed {
+		if f.err != errDeleteCancelled {
+			lines = append(lines, fmt.Sprintf("    - %s: %s", repoKey(f.repo), f.err))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nFailed deletions:\n%s\n", strings.Join(lines, "\n"))
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of durations sorted in
+// ascending order
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// printDeleteTimings prints the slowest n deletions along with the p50 and p95 latencies
+func printDeleteTimings(w io.Writer, timings []deleteTiming, n int) {
+	if len(timings) == 0 {
+		return
+	}
+
+	slowest := slices.Clone(timings)
+	slices.SortFunc(slowest, func(a, b deleteTiming) int {
+		return cmp.Compare(b.duration, a.duration)
+	})
+
+	durations := make([]time.Duration, len(timings))
+	for i, t := range timings {
+		durations[i] = t.duration
+	}
+	slices.Sort(durations)
+
+	fmt.Fprintf(
+		w,
+		"\nDeletion latency: p50 %s, p95 %s\n",
+		percentile(durations, 50).Round(time.Millisecond),
+		percentile(durations, 95).Round(time.Millisecond))
+
+	fmt.Fprintf(w, "\nSlowest deletions:\n")
+	for _, t := range slowest[:min(n, len(slowest))] {
+		fmt.Fprintf(w, "    - %s (%s)\n", t.repo.URL, t.duration.Round(time.Millisecond))
+	}
+}
+
+type cliConfig struct {
+	// Required
+	stdout  io.Writer
+	stderr  io.Writer
 	version string
 
 	// Optional
-	flagErrorHandling flag.ErrorHandling
-	fetchForkedRepos  func(
+	stdin                  io.Reader
+	baseURL                string
+	flagErrorHandling      flag.ErrorHandling
+	lookupEnv              func(key string) (string, bool)
+	isTerminal             func(w io.Writer) bool
+	fetchAuthenticatedUser func(ctx context.Context, baseURL, token string) (user, error)
+	fetchUser              func(ctx context.Context, baseURL, owner, token string) (user, error)
+	fetchForkedRepos       func(
 		ctx context.Context,
 		baseURL,
 		owner,
-		token string,
+		token,
+		endpoint string,
 		perPage,
-		maxPage int) ([]repo, error)
+		maxPage int) ([]Repo, error)
+	searchForkedRepos func(
+		ctx context.Context,
+		baseURL,
+		owner,
+		token,
+		query string,
+		perPage,
+		maxPage int) ([]Repo, error)
+
+	filterForkedRepos func(forkedRepos []Repo, opts filterOptions) ([]Repo, []Repo)
 
-	filterForkedRepos func(
-		forkedRepos []repo,
-		protectedRepos []string,
-		olderThanDays int) ([]repo, []repo)
+	editFile func(path string) error
 
-	deleteRepos func(ctx context.Context, baseURL, token string, repos []repo) error
+	deleteRepos func(
+		ctx context.Context,
+		baseURL,
+		token string,
+		repos []Repo) (deleteResult, error)
+
+	// Set on the copy running each sweep of --watch
+	watching bool
 }
 
 func NewCLIConfig(
@@ -247,9 +1222,18 @@ func NewCLIConfig(
 		stderr:  stderr,
 		version: version,
 
-		flagErrorHandling: flag.ExitOnError,
-		fetchForkedRepos:  fetchForkedRepos,
-		deleteRepos:       deleteRepos,
+		stdin:                  os.Stdin,
+		baseURL:                defaultBaseURL,
+		flagErrorHandling:      flag.ExitOnError,
+		lookupEnv:              os.LookupEnv,
+		isTerminal:             isTerminal,
+		fetchAuthenticatedUser: fetchAuthenticatedUser,
+		fetchUser:              fetchUser,
+		fetchForkedRepos:       fetchForkedRepos,
+		searchForkedRepos:      searchForkedRepos,
+		filterForkedRepos:      filterForkedRepos,
+		editFile:               runEditor,
+		deleteRepos:            deleteRepos,
 	}
 }
 
@@ -259,31 +1243,86 @@ func (c *cliConfig) withFlagErrorHandling(h flag.ErrorHandling) *cliConfig {
 	return c
 }
 
+func (c *cliConfig) withStdin(stdin io.Reader) *cliConfig {
+	c.stdin = stdin
+	return c
+}
+
+func (c *cliConfig) withBaseURL(baseURL string) *cliConfig {
+	c.baseURL = baseURL
+	return c
+}
+
+func (c *cliConfig) withLookupEnv(f func(key string) (string, bool)) *cliConfig {
+	c.lookupEnv = f
+	return c
+}
+
+func (c *cliConfig) withIsTerminal(f func(w io.Writer) bool) *cliConfig {
+	c.isTerminal = f
+	return c
+}
+
+func (c *cliConfig) withFetchAuthenticatedUser(
+	f func(ctx context.Context, baseURL, token string) (user, error)) *cliConfig {
+
+	c.fetchAuthenticatedUser = f
+	return c
+}
+
+func (c *cliConfig) withFetchUser(
+	f func(ctx context.Context, baseURL, owner, token string) (user, error)) *cliConfig {
+
+	c.fetchUser = f
+	return c
+}
+
 func (c *cliConfig) withFetchForkedRepos(
 	f func(
 		ctx context.Context,
 		baseURL,
 		owner,
-		token string,
+		token,
+		endpoint string,
 		perPage,
-		maxPage int) ([]repo, error)) *cliConfig {
+		maxPage int) ([]Repo, error)) *cliConfig {
 
 	c.fetchForkedRepos = f
 	return c
 }
 
-func (c *cliConfig) withFilterForkedRepos(
+func (c *cliConfig) withSearchForkedRepos(
 	f func(
-		forkedRepos []repo,
-		protectedRepos []string,
-		olderThanDays int) ([]repo, []repo)) *cliConfig {
+		ctx context.Context,
+		baseURL,
+		owner,
+		token,
+		query string,
+		perPage,
+		maxPage int) ([]Repo, error)) *cliConfig {
+
+	c.searchForkedRepos = f
+	return c
+}
+
+func (c *cliConfig) withFilterForkedRepos(
+	f func(forkedRepos []Repo, opts filterOptions) ([]Repo, []Repo)) *cliConfig {
 
 	c.filterForkedRepos = f
 	return c
 }
 
+func (c *cliConfig) withEditFile(f func(path string) error) *cliConfig {
+	c.editFile = f
+	return c
+}
+
 func (c *cliConfig) withDeleteRepos(
-	f func(ctx context.Context, baseURL, token string, repos []repo) error) *cliConfig {
+	f func(
+		ctx context.Context,
+		baseURL,
+		token string,
+		repos []Repo) (deleteResult, error)) *cliConfig {
 
 	c.deleteRepos = f
 	return c
@@ -300,72 +1339,1334 @@ func (s *stringSlice) String() string {
 	return strings.Join(*s, ", ")
 }
 
+// flagEnvName returns the environment variable that sets the named flag
+func flagEnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// setFlagsFromEnv sets every flag that has a matching environment variable. It runs
+// after parsing and skips the flags passed on the command line, so those take
+// precedence, repeatable ones included. Repeatable flags take a comma separated
+// list.
+func setFlagsFromEnv(fs *flag.FlagSet, lookupEnv func(key string) (string, bool)) error {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(flagEnvName(f.Name))
+		if !ok || passed[f.Name] || err != nil {
+			return
+		}
+
+		values := []string{value}
+		if _, ok := f.Value.(*stringSlice); ok {
+			values = strings.Split(value, ",")
+		}
+
+		for _, v := range values {
+			if setErr := f.Value.Set(strings.TrimSpace(v)); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, flagEnvName(f.Name), setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
 func (c *cliConfig) CLI(args []string) int {
+	start := time.Now()
+
 	var (
-		owner          string
-		token          string
-		perPage        int
-		maxPage        int
-		olderThanDays  int
-		version        bool
-		delete         bool
-		protectedRepos stringSlice
-
-		stdout            = c.stdout
-		stderr            = c.stderr
-		versionNumber     = c.version
-		flagErrorHandling = c.flagErrorHandling
-		fetchForkedRepos  = c.fetchForkedRepos
-		deleteRepos       = c.deleteRepos
+		owner           string
+		ownerFromToken  bool
+		token           string
+		perPage         int
+		maxPage         int
+		startPage       int
+		bestEffort      bool
+		allowIncomplete bool
+		visibility      string
+		apiSort         string
+		apiDirection    string
+		olderThanDays   int
+		minIdleDays     int
+		pushedSince     string
+		pushedUntil     string
+		activityMode    string
+		ageBasis        string
+		version         bool
+		verbose         bool
+		debug           bool
+		stream          bool
+		pretty          bool
+		healthCheck     bool
+		watch           time.Duration
+		confirmOwner    bool
+		crossOwner      bool
+		delete          bool
+		onlyArchived    bool
+		exclArchived    bool
+		protectOrphans  bool
+		deleteOrphans   bool
+		maxDelete       int
+		perOwnerLimit   int
+		maxList         int
+		hideGuarded     bool
+		hideUnguarded   bool
+		maxAhead        int
+		compareConc     int
+		autoConc        bool
+		keepLatest      int
+		firstPageOnly   bool
+		emptyOnly       bool
+		protectOthers   bool
+		protectIssues   bool
+		minStars        int
+		maxStars        int
+		recentBranch    bool
+		maxBranches     int
+		parentOwner     string
+		diffUpstreamURL bool
+		protectIfFile   string
+		confirmEachRepo bool
+		interactiveEdit bool
+		transferTo      string
+		detach          bool
+		deletesPerMin   int
+		failFast        bool
+		continueOnError bool
+		reportFormat    string
+		deleteOrder     string
+		yes             bool
+		search          string
+		ownerType       string
+		tokenCommand    string
+		nameExclude     string
+		guardFile       string
+		guardFullName   bool
+		cacheFile       string
+		outputFormat    string
+		useGHAuth       bool
+		reportPath      string
+		reportAppend    bool
+		diffPath        string
+		assertEmpty     bool
+		planPath        string
+		ghScriptPath    string
+		applyPath       string
+		decisionsPath   string
+		notifyURL       string
+		accept          string
+		apiVersion      string
+		retries         int
+		retryBudget     int
+		abortRateLimit  bool
+		failureRate     float64
+		maxRespBytes    int64
+		maxBodyLog      int64
+		perPageParam    string
+		omitTypeParam   bool
+		noForkFilter    bool
+		inclSources     bool
+		notifyRepos     bool
+		summaryPath     string
+		protectedRepos  stringSlice
+		requiredScopes  stringSlice
+
+		stdout                 = c.stdout
+		stdin                  = c.stdin
+		stderr                 = c.stderr
+		versionNumber          = c.version
+		baseURL                = c.baseURL
+		flagErrorHandling      = c.flagErrorHandling
+		lookupEnv              = c.lookupEnv
+		isTerminal             = c.isTerminal
+		fetchAuthenticatedUser = c.fetchAuthenticatedUser
+		fetchUser              = c.fetchUser
+		fetchForkedRepos       = c.fetchForkedRepos
+		searchForkedRepos      = c.searchForkedRepos
+		filterForkedRepos      = c.filterForkedRepos
+		deleteRepos            = c.deleteRepos
 	)
 
 	// Parsing command-line flags
 	fs := flag.NewFlagSet("fork-sweeper", flagErrorHandling)
 	fs.SetOutput(stdout)
 
-	fs.StringVar(&owner, "owner", "", "GitHub repo owner (required)")
+	fs.StringVar(&owner, "owner", "", "GitHub repo owner or the URL of its profile (required)")
+	fs.BoolVar(&ownerFromToken,
+		"owner-from-token",
+		false,
+		"Sweep the forks of the user the token belongs to instead of --owner")
 	fs.StringVar(&token, "token", "", "GitHub access token (required)")
-	fs.IntVar(&perPage, "per-page", 100, "Number of forked repos fetched per page")
+	fs.StringVar(&tokenCommand,
+		"token-command",
+		"",
+		"Shell command printing the token, used when --token isn't set")
+	fs.BoolVar(&useGHAuth,
+		"use-gh-auth",
+		false,
+		"Use the token stored by 'gh auth login' when --token isn't set")
+	fs.StringVar(&ownerType,
+		"owner-type",
+		ownerTypeAuto,
+		"Whether the owner is a 'user' or an 'org', or 'auto' to detect it")
+	fs.IntVar(&perPage,
+		"per-page",
+		maxPerPage,
+		"Number of forked repos fetched per page, GitHub serves at most 100")
 	fs.IntVar(&maxPage, "max-page", 100, "Maximum number of pages to fetch")
+	fs.IntVar(&startPage, "start-page", 1, "Page to start fetching at, up to max-page")
+	fs.StringVar(&visibility,
+		"visibility",
+		visibilityAll,
+		"List 'all', 'public' or 'private' forks, applies when listing your own forks")
+	fs.StringVar(&apiSort,
+		"api-sort",
+		apiSortPushed,
+		"Have the API list repos by 'created', 'updated', 'pushed' or 'full_name', '' for its default")
+	fs.StringVar(&apiDirection,
+		"api-direction",
+		apiDirectionDesc,
+		"Have the API list repos in 'asc' or 'desc' order, '' for its default")
+	fs.BoolVar(&bestEffort,
+		"best-effort",
+		false,
+		"Warn about and skip listing pages that fail instead of aborting the scan")
+	fs.BoolVar(&allowIncomplete,
+		"allow-incomplete",
+		false,
+		"Delete even if best-effort skipped pages or the search was incomplete, forks not listed are never deleted")
+	fs.BoolVar(&firstPageOnly,
+		"first-page-only",
+		false,
+		"Quickly scan only the first page of forks, same as --max-page 1")
+	fs.BoolVar(&inclSources,
+		"include-sources",
+		false,
+		"List source repos along with forks, read-only, sources are never deleted")
+	fs.StringVar(&search,
+		"repos-from-search",
+		"",
+		"Select forks with a repository search query instead of listing them all")
 	fs.IntVar(&olderThanDays,
 		"older-than-days",
 		60,
-		"Fetch forked repos modified more than n days ago")
+		"Select forks with no activity (creation, update or push) in the last n days")
+	fs.IntVar(&minIdleDays,
+		"min-idle-days",
+		0,
+		"Select forks not pushed to in the last n days, ignoring other activity (0 disables)")
+	fs.StringVar(&pushedSince,
+		"pushed-since",
+		"",
+		"Only delete forks last pushed on or after this date, e.g. 2021-01-01")
+	fs.StringVar(&pushedUntil,
+		"pushed-until",
+		"",
+		"Only delete forks last pushed on or before this date, e.g. 2021-03-31")
+	fs.IntVar(&keepLatest,
+		"keep-latest",
+		0,
+		"Always keep the n most recently active forks regardless of age")
+	fs.StringVar(&activityMode,
+		"activity-mode",
+		activityModeAny,
+		"Treat a fork as active if 'any' or 'all' of its timestamps are recent")
+	fs.StringVar(&ageBasis,
+		"age-basis",
+		ageBasisActivity,
+		"Timestamps that count as activity: 'activity' (created, updated, pushed) or 'changes' (updated, pushed)")
 	fs.BoolVar(&version, "version", false, "Print version")
+	fs.BoolVar(&verbose, "verbose", false, "Print detailed diagnostics")
+	fs.BoolVar(&debug,
+		"debug",
+		false,
+		"Log every API request with its status and GitHub request ID, implies verbose logging of failures")
+	fs.BoolVar(&stream, "stream", false, "Print each keep/delete decision as it's made")
+	fs.BoolVar(&pretty,
+		"pretty",
+		false,
+		"List forks as aligned, colored columns when stdout is a terminal")
+	fs.BoolVar(&confirmOwner,
+		"confirm-token-owner",
+		false,
+		"Refuse to run if the token doesn't belong to the owner (orgs are exempt)")
+	fs.BoolVar(&crossOwner,
+		"allow-cross-owner",
+		false,
+		"Only warn when confirm-token-owner finds a mismatch")
+	fs.BoolVar(&healthCheck, "health-check", false, "Verify API connectivity, token and owner, then exit")
+	fs.DurationVar(&watch,
+		"watch",
+		0,
+		"Keep running and sweep every interval, e.g. 6h, until interrupted")
 	fs.BoolVar(&delete, "delete", false, "Delete forked repos")
+	fs.BoolVar(&onlyArchived, "only-archived", false, "Only delete archived forks")
+	fs.BoolVar(&exclArchived, "exclude-archived", false, "Never delete archived forks")
+	fs.BoolVar(&protectOrphans,
+		"protect-orphans",
+		false,
+		"Never delete orphaned forks whose upstream no longer exists")
+	fs.BoolVar(&deleteOrphans,
+		"delete-orphans",
+		false,
+		"Delete orphaned forks whose upstream no longer exists regardless of age")
+	fs.BoolVar(&emptyOnly,
+		"delete-empty-only",
+		false,
+		"Only delete pristine forks that are empty or have no commits ahead of their upstream")
+	fs.BoolVar(&protectOthers,
+		"protect-if-others-contributed",
+		false,
+		"Keep forks with commits ahead of their upstream authored by anyone but the owner")
+	fs.BoolVar(&protectIssues,
+		"protect-if-issues-open",
+		false,
+		"Keep forks with open issues, which GitHub counts along with open pull requests")
+	fs.IntVar(&minStars,
+		"min-stars",
+		-1,
+		"Only delete forks with at least n stars (-1 disables the check)")
+	fs.IntVar(&maxStars,
+		"max-stars",
+		-1,
+		"Keep forks with more than n stars, which others find useful (-1 disables the check)")
+	fs.BoolVar(&recentBranch,
+		"exclude-recent-default-branch-push",
+		false,
+		"Keep forks whose default branch, not just any branch, has commits within older-than-days")
+	fs.IntVar(&maxBranches,
+		"protect-branch-count",
+		-1,
+		"Keep forks with more than n branches, a sign of work of your own (-1 disables the check)")
+	fs.StringVar(&parentOwner,
+		"parent-owner",
+		"",
+		"Only delete forks of repos owned by this user or org")
+	fs.BoolVar(&diffUpstreamURL,
+		"diff-upstream-url",
+		false,
+		"Show a link to each fork's compare view against its upstream for manual review")
+	fs.StringVar(&protectIfFile,
+		"protect-if-file",
+		"",
+		"Keep forks that have this file, e.g. .keep, on their default branch")
+	fs.IntVar(&maxAhead,
+		"max-ahead",
+		-1,
+		"Keep forks more than n commits ahead of their upstream (-1 disables the check)")
+	fs.IntVar(&compareConc,
+		"compare-concurrency",
+		defaultReadConcurrency,
+		"Concurrent requests checking each fork's upstream or marker file for the orphan, ahead, parent and marker filters")
+	fs.BoolVar(&autoConc,
+		"concurrency-auto",
+		false,
+		"Start the per-fork checks at 2 concurrent requests and scale up to compare-concurrency with the rate limit headroom")
+	fs.StringVar(&deleteOrder,
+		"delete-order",
+		"",
+		"Delete the 'oldest' or 'newest' forks first (default listing order)")
+	fs.IntVar(&maxDelete, "max-delete", 0, "Abort if more than n forks would be deleted (0 means no limit)")
+	fs.IntVar(&perOwnerLimit,
+		"per-owner-limit",
+		0,
+		"Delete at most n forks of each owner per run, skipping the rest (0 means no limit)")
+	fs.IntVar(&maxList,
+		"max-list",
+		0,
+		"Show at most n forks of each list in the text output, deletion still acts on all (0 means no limit)")
+	fs.BoolVar(&hideGuarded,
+		"hide-guarded",
+		false,
+		"Leave the guarded forks out of the text output, only listing what will be deleted")
+	fs.BoolVar(&hideUnguarded,
+		"hide-unguarded",
+		false,
+		"Leave the unguarded forks out of the text output, only listing what will be kept")
+	fs.StringVar(&transferTo,
+		"transfer-to",
+		"",
+		"Transfer unguarded forks to the given owner, e.g. an archive org, instead of deleting")
+	fs.BoolVar(&detach,
+		"detach",
+		false,
+		"Detach unguarded forks from their upstream's network instead of deleting, where the host supports it")
+	fs.BoolVar(&confirmEachRepo,
+		"confirm-each",
+		false,
+		"Ask before deleting each fork, answering 'a' approves the rest and 'q' quits")
+	fs.BoolVar(&interactiveEdit,
+		"interactive-edit",
+		false,
+		"Open the forks to delete in $EDITOR and only delete the ones whose lines are left")
+	fs.IntVar(&deletesPerMin,
+		"max-delete-per-minute",
+		0,
+		"Throttle deletions to at most n per minute (0 means no limit)")
+	fs.BoolVar(&failFast,
+		"fail-fast",
+		false,
+		"Cancel the remaining deletions as soon as one fails")
+	fs.BoolVar(&continueOnError,
+		"continue-on-error",
+		false,
+		"Attempt every deletion even if some fail, the default")
+	fs.BoolVar(&yes, "yes", false, "Proceed with deletion even if it exceeds max-delete")
 	fs.Var(&protectedRepos, "guard", "List of repos to protect from deletion (fuzzy match name)")
+	fs.Var(&requiredScopes,
+		"require-scope",
+		"Refuse to run unless the token has this scope, e.g. delete_repo (repeatable)")
+	fs.StringVar(&guardFile,
+		"guard-file",
+		"",
+		"JSON file of guard rules with a pattern, match type and reason")
+	fs.BoolVar(&guardFullName,
+		"guard-full-name",
+		false,
+		"Match guard patterns against owner/name instead of just the repo name")
+	fs.StringVar(&nameExclude,
+		"name-regex-exclude",
+		"",
+		"Leave out forks whose name matches the regular expression entirely")
+	fs.StringVar(&baseURL, "api-url", baseURL, "API base URL, e.g. https://gitea.example.com/api/v1")
+	fs.StringVar(&baseURL, "base-url", baseURL, "Alias of --api-url, e.g. to point at a local mock server")
+	fs.StringVar(&perPageParam,
+		"per-page-param",
+		defaultPerPageParam,
+		"Name of the page size query param ('limit' on Gitea and Forgejo)")
+	fs.BoolVar(&omitTypeParam,
+		"no-type-param",
+		false,
+		"Don't send type=forks for hosts that don't support it, filter forks client-side")
+	fs.BoolVar(&noForkFilter,
+		"no-fork-filter",
+		false,
+		"Trust type=forks and skip the client-side fork check, for hosts that don't set the fork field")
+	fs.StringVar(&accept, "accept", defaultAccept, "Accept header sent with API requests")
+	fs.StringVar(&apiVersion, "api-version", defaultAPIVersion, "GitHub API version sent with API requests")
+	fs.StringVar(&outputFormat,
+		"output-format",
+		outputFormatText,
+		"Print the decisions as 'text', 'json' or 'csv', progress goes to stderr for the latter")
+	fs.StringVar(&cacheFile,
+		"cache-file",
+		"",
+		"Cache responses with their ETags in the given file and revalidate them on later runs")
+	fs.StringVar(&reportPath, "report", "", "Write a JSON report of the run's decisions to the given path")
+	fs.StringVar(&reportFormat,
+		"report-format",
+		reportFormatJSON,
+		"Format of the report, 'json' or 'junit' for CI test result dashboards")
+	fs.BoolVar(&reportAppend,
+		"report-append",
+		false,
+		"Append the report to an audit log as JSON Lines, or CSV for a .csv path, tagging entries with a run ID")
+	fs.StringVar(&diffPath,
+		"dry-run-diff",
+		"",
+		"Print what changed since the previous run's report at the given path")
+	fs.BoolVar(&assertEmpty,
+		"dry-run-assert-empty",
+		false,
+		"Fail with exit code 5 if any forks would be deleted, listing them, e.g. to catch forks made against policy in CI")
+	fs.StringVar(&planPath,
+		"plan-file",
+		"",
+		"Write the forks that would be deleted to a JSON plan at the given path")
+	fs.StringVar(&ghScriptPath,
+		"emit-gh-script",
+		"",
+		"Write a script of 'gh repo delete' commands for the forks that would be deleted to the given path, or - for stdout, instead of deleting them")
+	fs.StringVar(&applyPath,
+		"apply",
+		"",
+		"Delete exactly the forks of a plan written by --plan-file, then exit")
+	fs.StringVar(&decisionsPath,
+		"apply-decisions",
+		"",
+		"Delete exactly the forks marked for deletion in a triaged CSV or JSON export, then exit")
+	fs.Int64Var(&maxRespBytes,
+		"max-response-bytes",
+		defaultMaxResponseBytes,
+		"Fail requests whose response body is larger than n bytes")
+	fs.Int64Var(&maxBodyLog,
+		"max-body-log-bytes",
+		defaultMaxBodyLogBytes,
+		"Truncate the failed response bodies logged by --verbose to n bytes")
+	fs.IntVar(&retries,
+		"retries",
+		0,
+		"Retry requests failing with network errors, 5xx, 429 or secondary rate limits up to n times")
+	fs.IntVar(&retryBudget, "retry-budget", 100, "Maximum number of retries across the whole run")
+	fs.BoolVar(&abortRateLimit,
+		"abort-on-rate-limit",
+		false,
+		"Fail as soon as a rate limit is hit instead of retrying after the wait GitHub asks for")
+	fs.Float64Var(&failureRate,
+		"simulate-failure-rate",
+		0,
+		"Testing only: fail this fraction of deletes against a local API URL")
+	fs.StringVar(&notifyURL, "notify-url", "", "Webhook URL to post a summary to after the run")
+	fs.BoolVar(&notifyRepos, "notify-repos", false, "Include the deleted repos in the webhook summary")
+	fs.StringVar(&summaryPath,
+		"summary-json-to",
+		"",
+		"Write the run's counts and duration as JSON to the given path, whatever the output format")
 
 	fs.Parse(args)
 
+	if err := setFlagsFromEnv(fs, lookupEnv); err != nil {
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return exitErr
+	}
+
 	// Printing version
 	if version {
 		fmt.Fprintln(stdout, versionNumber)
 		return exitOk
 	}
 
+	// Fetching the token from an external program
+	if token == "" && tokenCommand != "" {
+		var err error
+		if token, err = runTokenCommand(context.Background(), tokenCommand); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+	}
+
+	// Reusing the gh CLI's stored token. Unless --api-url is set, the host
+	// comes from GH_HOST like it does for gh, defaulting to github.com
+	if token == "" && useGHAuth {
+		host := ghHostFromAPIURL(baseURL)
+		if ghHost, ok := lookupEnv("GH_HOST"); ok && ghHost != "" && baseURL == c.baseURL {
+			host, baseURL = ghHost, ghHostAPIURL(ghHost)
+		}
+
+		var err error
+		if token, err = ghToken(ghHostsPath(lookupEnv), host); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+	}
+
 	// Validating required arguments
-	if owner == "" || token == "" {
+	if (owner == "" && !ownerFromToken) || token == "" {
 		fmt.Fprintln(stderr, "Error: owner and token are required")
 		fs.PrintDefaults()
 		return exitErr
 	}
 
-	ctx := context.Background()
-	baseURL := "https://api.github.com"
+	if owner != "" && ownerFromToken {
+		fmt.Fprintln(stderr, "Error: owner and owner-from-token are mutually exclusive")
+		return exitErr
+	}
+
+	// Accepting a pasted profile, org or repo URL as the owner
+	if owner != "" {
+		parsed, err := parseOwner(owner)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+		owner = parsed
+	}
+
+	if activityMode != activityModeAny && activityMode != activityModeAll {
+		fmt.Fprintf(stderr, "Error: activity-mode must be 'any' or 'all', got '%s'\n", activityMode)
+		return exitErr
+	}
+
+	if minStars >= 0 && maxStars >= 0 && minStars > maxStars {
+		fmt.Fprintf(
+			stderr, "Error: min-stars %d can't be greater than max-stars %d\n", minStars, maxStars)
+		return exitErr
+	}
+
+	if minIdleDays < 0 {
+		fmt.Fprintf(stderr, "Error: min-idle-days can't be negative, got %d\n", minIdleDays)
+		return exitErr
+	}
+
+	pushedWindow, windowErr := parsePushWindow(pushedSince, pushedUntil)
+	if windowErr != nil {
+		fmt.Fprintf(stderr, "Error: %s\n", windowErr)
+		return exitErr
+	}
+
+	if visibility != visibilityAll && visibility != visibilityPublic && visibility != visibilityPrivate {
+		fmt.Fprintf(
+			stderr, "Error: visibility must be 'all', 'public' or 'private', got '%s'\n", visibility)
+		return exitErr
+	}
+
+	switch apiSort {
+	case "", apiSortCreated, apiSortUpdated, apiSortPushed, apiSortFullName:
+	default:
+		fmt.Fprintf(stderr,
+			"Error: api-sort must be 'created', 'updated', 'pushed' or 'full_name', got '%s'\n", apiSort)
+		return exitErr
+	}
+
+	if apiDirection != "" && apiDirection != apiDirectionAsc && apiDirection != apiDirectionDesc {
+		fmt.Fprintf(stderr, "Error: api-direction must be 'asc' or 'desc', got '%s'\n", apiDirection)
+		return exitErr
+	}
+
+	if ageBasis != ageBasisActivity && ageBasis != ageBasisChanges {
+		fmt.Fprintf(stderr, "Error: age-basis must be 'activity' or 'changes', got '%s'\n", ageBasis)
+		return exitErr
+	}
+
+	if ownerType != ownerTypeUser && ownerType != ownerTypeOrg && ownerType != ownerTypeAuto {
+		fmt.Fprintf(stderr, "Error: owner-type must be 'user', 'org' or 'auto', got '%s'\n", ownerType)
+		return exitErr
+	}
+
+	if outputFormat != outputFormatText &&
+		outputFormat != outputFormatJSON &&
+		outputFormat != outputFormatCSV {
+		fmt.Fprintf(stderr, "Error: output-format must be 'text', 'json' or 'csv', got '%s'\n", outputFormat)
+		return exitErr
+	}
+
+	if ghScriptPath == ghScriptStdout && outputFormat != outputFormatText {
+		fmt.Fprintf(stderr, "Error: emit-gh-script - and output-format %s both write to stdout\n", outputFormat)
+		return exitErr
+	}
+
+	// Machine-readable output keeps stdout clean by moving progress to stderr,
+	// and so does a script written to stdout
+	progress := stdout
+	if outputFormat != outputFormatText || ghScriptPath == ghScriptStdout {
+		progress = stderr
+	}
+
+	if reportFormat != reportFormatJSON && reportFormat != reportFormatJUnit {
+		fmt.Fprintf(stderr, "Error: report-format must be 'json' or 'junit', got '%s'\n", reportFormat)
+		return exitErr
+	}
+
+	if reportAppend && (reportPath == "" || reportFormat != reportFormatJSON) {
+		fmt.Fprintln(stderr, "Error: report-append requires report and can't be combined with report-format junit")
+		return exitErr
+	}
+
+	if onlyArchived && exclArchived {
+		fmt.Fprintln(stderr, "Error: only-archived and exclude-archived are mutually exclusive")
+		return exitErr
+	}
+
+	if firstPageOnly {
+		maxPage = 1
+	}
+
+	if perPage < 1 {
+		fmt.Fprintf(stderr, "Error: per-page must be positive, got %d\n", perPage)
+		return exitErr
+	}
+
+	// Smaller pages only cost more requests on GitHub, other hosts may cap pages lower
+	if perPage < maxPerPage && perPageParam == defaultPerPageParam {
+		fmt.Fprintf(stderr,
+			"Warning: per-page %d takes more requests than GitHub's maximum of %d\n", perPage, maxPerPage)
+	}
+
+	if startPage < 1 || startPage > maxPage {
+		fmt.Fprintf(
+			stderr, "Error: start-page must be between 1 and max-page %d, got %d\n", maxPage, startPage)
+		return exitErr
+	}
+
+	if allowIncomplete && !bestEffort && search == "" {
+		fmt.Fprintln(stderr, "Error: allow-incomplete requires best-effort or repos-from-search")
+		return exitErr
+	}
+
+	if failFast && continueOnError {
+		fmt.Fprintln(stderr, "Error: fail-fast and continue-on-error are mutually exclusive")
+		return exitErr
+	}
+
+	if deletesPerMin < 0 {
+		fmt.Fprintf(stderr, "Error: max-delete-per-minute can't be negative, got %d\n", deletesPerMin)
+		return exitErr
+	}
+
+	if maxList < 0 {
+		fmt.Fprintf(stderr, "Error: max-list can't be negative, got %d\n", maxList)
+		return exitErr
+	}
+
+	if keepLatest < 0 {
+		fmt.Fprintf(stderr, "Error: keep-latest can't be negative, got %d\n", keepLatest)
+		return exitErr
+	}
+
+	if maxRespBytes <= 0 {
+		fmt.Fprintf(stderr, "Error: max-response-bytes must be positive, got %d\n", maxRespBytes)
+		return exitErr
+	}
+
+	if compareConc <= 0 {
+		fmt.Fprintf(stderr, "Error: compare-concurrency must be positive, got %d\n", compareConc)
+		return exitErr
+	}
+
+	if maxBodyLog <= 0 {
+		fmt.Fprintf(stderr, "Error: max-body-log-bytes must be positive, got %d\n", maxBodyLog)
+		return exitErr
+	}
+
+	if failureRate < 0 || failureRate > 1 {
+		fmt.Fprintf(stderr, "Error: simulate-failure-rate must be between 0 and 1, got %v\n", failureRate)
+		return exitErr
+	}
+
+	if failureRate > 0 && !isLocalURL(baseURL) {
+		fmt.Fprintln(stderr, "Error: simulate-failure-rate can only be used with a local api-url")
+		return exitErr
+	}
+
+	if delete && transferTo != "" {
+		fmt.Fprintln(stderr, "Error: delete and transfer-to are mutually exclusive")
+		return exitErr
+	}
+
+	if detach && (delete || transferTo != "" || applyPath != "" || decisionsPath != "") {
+		fmt.Fprintln(stderr, "Error: detach can't be combined with delete, transfer-to, apply or apply-decisions")
+		return exitErr
+	}
+
+	if inclSources && (delete || transferTo != "") {
+		fmt.Fprintln(stderr, "Error: include-sources is read-only, drop delete and transfer-to")
+		return exitErr
+	}
+
+	if assertEmpty && (delete || transferTo != "" || applyPath != "" || decisionsPath != "") {
+		fmt.Fprintln(
+			stderr, "Error: dry-run-assert-empty is read-only, drop delete, transfer-to, apply and apply-decisions")
+		return exitErr
+	}
+
+	if ghScriptPath != "" && (delete || transferTo != "" || applyPath != "" || decisionsPath != "") {
+		fmt.Fprintln(
+			stderr, "Error: emit-gh-script leaves deleting to gh, drop delete, transfer-to, apply and apply-decisions")
+		return exitErr
+	}
+
+	if applyPath != "" && (planPath != "" || transferTo != "") {
+		fmt.Fprintln(stderr, "Error: apply can't be combined with plan-file or transfer-to")
+		return exitErr
+	}
+
+	if decisionsPath != "" && (applyPath != "" || planPath != "" || transferTo != "") {
+		fmt.Fprintln(stderr, "Error: apply-decisions can't be combined with apply, plan-file or transfer-to")
+		return exitErr
+	}
+
+	if noForkFilter && (omitTypeParam || inclSources) {
+		fmt.Fprintln(stderr, "Error: no-fork-filter relies on type=forks, drop no-type-param and include-sources")
+		return exitErr
+	}
+
+	if protectOrphans && deleteOrphans {
+		fmt.Fprintln(stderr, "Error: protect-orphans and delete-orphans are mutually exclusive")
+		return exitErr
+	}
+
+	if watch < 0 {
+		fmt.Fprintf(stderr, "Error: watch interval can't be negative, got %s\n", watch)
+		return exitErr
+	}
+
+	if watch > 0 &&
+		(confirmEachRepo || interactiveEdit || healthCheck || applyPath != "" || decisionsPath != "") {
+		fmt.Fprintln(stderr,
+			"Error: watch can't be combined with confirm-each, interactive-edit, health-check, apply or apply-decisions")
+		return exitErr
+	}
+
+	if interactiveEdit && !delete && transferTo == "" {
+		fmt.Fprintln(stderr, "Error: interactive-edit requires delete or transfer-to")
+		return exitErr
+	}
+
+	if deleteOrder != "" && deleteOrder != deleteOrderOldest && deleteOrder != deleteOrderNewest {
+		fmt.Fprintf(stderr, "Error: delete-order must be 'oldest' or 'newest', got '%s'\n", deleteOrder)
+		return exitErr
+	}
+
+	guardRules := guardRulesFromNames(protectedRepos)
+	if guardFile != "" {
+		rules, err := readGuardRules(guardFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+		guardRules = append(guardRules, rules...)
+	}
+
+	var excludeName *regexp.Regexp
+	if nameExclude != "" {
+		var err error
+		if excludeName, err = regexp.Compile(nameExclude); err != nil {
+			fmt.Fprintf(stderr, "Error: invalid name-regex-exclude: %s\n", err)
+			return exitErr
+		}
+	}
+
+	// Sweeping on a schedule, each sweep being a full run of its own
+	if watch > 0 && !c.watching {
+		return c.watch(args, watch)
+	}
+
+	// Loading the ETag cache and saving it on the way out, failures only warn
+	var cache *etagCache
+	if cacheFile != "" {
+		var err error
+		if cache, err = loadETagCache(cacheFile); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+		defer func() {
+			if verbose {
+				fmt.Fprintf(progress, "\nServed %d responses from the cache\n", cache.hits)
+			}
+			if err := cache.save(cacheFile); err != nil {
+				fmt.Fprintf(stderr, "Warning: %s\n", err)
+			}
+		}()
+	}
+
+	// Logging the body of failed responses to stderr when verbose, and every
+	// request when debugging
+	var errorBodyLog, requestLog io.Writer
+	if verbose || debug {
+		errorBodyLog = stderr
+	}
+	if debug {
+		requestLog = stderr
+	}
+
+	// Cancelling in-flight requests on Ctrl-C or SIGTERM, the run then exits
+	// with exitInterrupted after reporting what it got done
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	quota := newQuotaTracker()
+	var autoConcurrency *concurrencyController
+	if autoConc {
+		autoConcurrency = newConcurrencyController(initialAutoConcurrency, compareConc)
+		defer func() {
+			if verbose {
+				fmt.Fprintf(progress,
+					"\nAuto concurrency settled at %d concurrent requests\n", autoConcurrency.current())
+			}
+		}()
+	}
+	ctx = withRequestConfig(ctx, requestConfig{
+		accept:     accept,
+		apiVersion: apiVersion,
+		retries:    retries,
+		budget:     newRetryBudget(retryBudget),
+
+		abortOnRateLimit: abortRateLimit,
+
+		maxResponseBytes: maxRespBytes,
+		cache:            cache,
+
+		errorBodyLog:    errorBodyLog,
+		maxBodyLogBytes: maxBodyLog,
+		requestLog:      requestLog,
+
+		perPageParam:  perPageParam,
+		omitTypeParam: omitTypeParam,
+		noForkFilter:  noForkFilter,
+
+		includeSources:  inclSources,
+		visibility:      visibility,
+		sort:            apiSort,
+		direction:       apiDirection,
+		readConcurrency: compareConc,
+		autoConcurrency: autoConcurrency,
+		startPage:       startPage,
+		bestEffort:      bestEffort,
+
+		failFast:            failFast,
+		quota:               quota,
+		deleteLimiter:       newRateLimiter(deletesPerMin, time.Minute),
+		deleteJitter:        newJitter(defaultDeleteJitter, time.Now().UnixNano()),
+		simulateFailureRate: failureRate,
+	})
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	// Resolving the owner from the token, which must belong to a user rather
+	// than an app or bot. Gitea and Forgejo don't report the account type.
+	if ownerFromToken {
+		u, err := fetchAuthenticatedUser(ctx, baseURL, token)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: could not resolve the owner from the token: %s\n", err)
+			return exitCodeFor(ctx, err)
+		}
+		if u.Login == "" || (u.Type != "" && u.Type != userTypeUser) {
+			fmt.Fprintf(
+				stderr,
+				"Error: token belongs to %s %s rather than a user, pass --owner instead\n",
+				strings.ToLower(u.Type),
+				u.Login)
+			return exitErr
+		}
+		owner = u.Login
+		fmt.Fprintf(progress, "\nResolved owner %s from the token\n", owner)
+	}
+
+	// Checking health without listing or deleting anything
+	if healthCheck {
+		fmt.Fprintf(stdout, "\nChecking GitHub API health for %s...\n", owner)
+		if err := checkHealth(ctx, stdout, baseURL, owner, token); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitCodeFor(ctx, err)
+		}
+		fmt.Fprintf(stdout, "\nHealth check passed\n")
+		return exitOk
+	}
+
+	// Checking that the token has the required scopes before anything is deleted.
+	// Fine-grained tokens don't report scopes, so they can't satisfy the check.
+	if len(requiredScopes) > 0 {
+		u, err := fetchAuthenticatedUser(ctx, baseURL, token)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: could not check the token's scopes: %s\n", err)
+			return exitCodeFor(ctx, err)
+		}
+		if u.scopes == nil {
+			fmt.Fprintln(stderr, "Error: the token doesn't report its scopes, fine-grained tokens can't satisfy require-scope")
+			return exitAuth
+		}
+		if missing := u.missingScopes(requiredScopes); len(missing) > 0 {
+			fmt.Fprintf(stderr, "Error: the token is missing the required scopes: %s\n", strings.Join(missing, ", "))
+			return exitAuth
+		}
+	}
+
+	// The decisions of the run, either filtered from the listing or read from
+	// a plan, and where the forks were listed from
+	var (
+		guardedRepos, unguardedRepos []Repo
+		reasons                      = map[string]string{}
+		listedFrom, endpointReason   string
+	)
+
+	// Writing the report and notifying the webhook once the run finishes,
+	// failures only warn. Failed deletions are recorded in the report by repoKey.
+	deleteErrs := map[string]string{}
+	aborted := "" // why the run refused to delete, recorded in the report and summary
+	finish := func(deletedRepos []Repo) {
+		if reportPath != "" {
+			rep := newReport(
+				owner, time.Now(), guardedRepos, unguardedRepos, deletedRepos, reasons)
+			rep.Aborted = aborted
+			for i, e := range rep.Repos {
+				rep.Repos[i].Error = deleteErrs[entryKey(e)]
+			}
+
+			write := writeReport
+			if reportFormat == reportFormatJUnit {
+				write = writeJUnitReport
+			}
+			if reportAppend {
+				runID := newClientRequestID()
+				write = func(path string, rep report) error { return appendReport(path, runID, rep) }
+			}
+			if err := write(reportPath, rep); err != nil {
+				fmt.Fprintf(stderr, "Warning: failed to write report: %s\n", err)
+			}
+		}
+
+		// Reporting how much of the rate limit the run used, as far as the
+		// response headers tell
+		usage := quota.usage()
+		if usage != nil && usage.Reset.IsZero() {
+			fmt.Fprintf(progress, "\nUsed ~%d API requests\n", usage.Used)
+		} else if usage != nil {
+			fmt.Fprintf(
+				progress,
+				"\nUsed ~%d API requests, %d remaining until %s\n",
+				usage.Used,
+				usage.Remaining,
+				usage.Reset.Format(time.RFC3339))
+		}
+
+		if summaryPath != "" {
+			sum := newSummary(
+				owner, start, time.Now(), guardedRepos, unguardedRepos, deletedRepos, len(deleteErrs))
+			sum.RateLimit = usage
+			sum.Endpoint, sum.EndpointReason = listedFrom, endpointReason
+			sum.Aborted = aborted
+			if err := writeSummary(summaryPath, sum); err != nil {
+				fmt.Fprintf(stderr, "Warning: failed to write summary: %s\n", err)
+			}
+		}
+
+		if notifyURL != "" {
+			n := newNotification(owner, guardedRepos, unguardedRepos, deletedRepos, notifyRepos)
+			if aborted != "" {
+				n.abort(aborted)
+			}
+			if err := sendNotification(ctx, notifyURL, n); err != nil {
+				fmt.Fprintf(stderr, "Warning: failed to send notification: %s\n", err)
+			}
+		}
+	}
+
+	// Deleting, or transferring, the unguarded repositories that make it past
+	// the limits and confirmations, whether filtered from the listing or planned
+	deleteUnguarded := func() int {
+		verb := "delete"
+		if transferTo != "" {
+			verb = "transfer"
+		}
+
+		if len(unguardedRepos) == 0 {
+			fmt.Fprintf(progress, "\nNo unguarded forked repositories to %s\n", verb)
+			finish(nil)
+			return exitOk
+		}
+
+		if deleteOrder != "" {
+			sortReposByActivity(unguardedRepos, deleteOrder)
+		}
+
+		// Capping the deletions per owner, the forks over the limit are skipped
+		toDelete := unguardedRepos
+		if perOwnerLimit > 0 {
+			var skipped map[string]int
+			toDelete, skipped = limitPerOwner(unguardedRepos, perOwnerLimit)
+			owners := make([]string, 0, len(skipped))
+			for owner := range skipped {
+				owners = append(owners, owner)
+			}
+			slices.Sort(owners)
+			for _, owner := range owners {
+				noun := "forks"
+				if skipped[owner] == 1 {
+					noun = "fork"
+				}
+				fmt.Fprintf(
+					progress,
+					"\nSkipping %d %s of %s over the per-owner-limit of %d\n",
+					skipped[owner],
+					noun,
+					owner,
+					perOwnerLimit)
+			}
+		}
+
+		if maxDelete > 0 && len(toDelete) > maxDelete && !yes {
+			aborted = fmt.Sprintf(
+				"refusing to %s %d forks, more than max-delete %d", verb, len(toDelete), maxDelete)
+			finish(nil)
+			fmt.Fprintf(stderr, "Error: %s; pass --yes to proceed\n", aborted)
+			return exitErr
+		}
+
+		// Curating the list in an editor, only the forks left in it are deleted
+		if interactiveEdit {
+			edited, err := editList(c.editFile, verb, toDelete, time.Now())
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: %s\n", err)
+				return exitErr
+			}
+			fmt.Fprintf(progress, "\n%d of %d forks left to %s in the editor\n", len(edited), len(toDelete), verb)
+			toDelete = edited
+			if len(toDelete) == 0 {
+				fmt.Fprintf(progress, "\nNo forks left to %s\n", verb)
+				finish(nil)
+				return exitOk
+			}
+		}
+
+		// Asking before each deletion, only the approved forks are deleted
+		if confirmEachRepo {
+			fmt.Fprintln(progress)
+			action := strings.ToUpper(verb[:1]) + verb[1:]
+			confirmed, err := confirmEach(ctx, stdin, progress, action, toDelete, time.Now())
+			if err != nil {
+				fmt.Fprintf(stderr, "Interrupted, skipping the forks to %s\n", verb)
+				finish(nil)
+				return exitCodeFor(ctx, err)
+			}
+			toDelete = confirmed
+			if len(toDelete) == 0 {
+				fmt.Fprintf(progress, "\nNo forks confirmed for %s\n", verb)
+				finish(nil)
+				return exitOk
+			}
+		}
+
+		// Transferring is asynchronous, GitHub only accepts the requests here
+		if transferTo != "" {
+			fmt.Fprintf(progress, "\nTransferring forked repositories to %s...\n", transferTo)
+			transferred, err := transferRepos(ctx, baseURL, token, transferTo, toDelete)
+
+			fmt.Fprintf(progress, "\nInitiated %d of %d transfers:\n", len(transferred), len(toDelete))
+			for _, repo := range transferred {
+				fmt.Fprintf(progress, "    - %s -> %s/%s\n", repo.URL, transferTo, repo.Name)
+			}
+			finish(nil)
+
+			if err != nil {
+				switch err.Error() {
+				case errSecondaryRateLimit.Error():
+					fmt.Fprintln(stderr, "Error: hit GitHub's secondary rate limit, retry later")
+				case ErrMsg403:
+					fmt.Fprintf(stderr, "Error: token does not have permission to transfer repos\n")
+				case ErrMsg404:
+					fmt.Fprintf(stderr, "Error: repo not found\n")
+				default:
+					fmt.Fprintf(stderr, "Error: %s\n", err)
+				}
+				return deleteExitCode(ctx, err, len(transferred))
+			}
+			return exitOk
+		}
+
+		fmt.Fprintf(progress, "\nDeleting forked repositories...\n")
+		result, err := deleteRepos(ctx, baseURL, token, toDelete)
+		if verbose {
+			printDeleteTimings(progress, result.timings, 5)
+		}
+		if err != nil {
+			// Reporting the deletions that did happen along with the failed ones
+			for _, f := range result.failed {
+				deleteErrs[repoKey(f.repo)] = f.err.Error()
+			}
+			finish(result.succeeded)
+
+			switch err.Error() {
+			case errSecondaryRateLimit.Error():
+				fmt.Fprintln(stderr, "Error: hit GitHub's secondary rate limit, slow down with --max-delete-per-minute")
+			case ErrMsg403:
+				fmt.Fprintf(stderr, "Error: token does not have permission to delete repos\n")
+			case ErrMsg404:
+				fmt.Fprintf(stderr, "Error: repo not found\n")
+			default:
+				fmt.Fprintf(stderr, "Error: %s\n", err)
+			}
+			cancelled := result.cancelled()
+			if failed := len(result.failed) - cancelled; failed > 1 {
+				fmt.Fprintf(stderr, "%d of %d deletions failed\n", failed, len(toDelete))
+			}
+			if cancelled > 0 {
+				fmt.Fprintf(stderr, "Cancelled %d remaining deletions after the first failure\n", cancelled)
+			}
+			printFailedDeletions(stderr, result.failed)
+			return deleteExitCode(ctx, err, len(result.succeeded))
+		}
+
+		noun := "forks"
+		if len(toDelete) == 1 {
+			noun = "fork"
+		}
+		fmt.Fprintf(
+			progress,
+			"\nDeleted %d %s in %s (finished at %s)\n",
+			len(toDelete),
+			noun,
+			time.Since(start).Round(100*time.Millisecond),
+			time.Now().Format(time.RFC3339))
+		finish(toDelete)
+		return exitOk
+	}
+
+	// Deleting exactly the forks of a reviewed plan, without listing or filtering
+	if applyPath != "" || decisionsPath != "" {
+		var p plan
+		var err error
+		if decisionsPath != "" {
+			p, err = readDecisions(decisionsPath, owner)
+		} else {
+			p, err = readPlan(applyPath)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+		if !strings.EqualFold(p.Owner, owner) {
+			fmt.Fprintf(stderr, "Error: plan is for %s, not %s\n", p.Owner, owner)
+			return exitErr
+		}
+
+		fmt.Fprintf(progress, "\nVerifying %d planned forks...\n", len(p.Repos))
+		planned, err := verifyPlan(ctx, stderr, baseURL, token, p)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitCodeFor(ctx, err)
+		}
+		if len(planned) == 0 {
+			fmt.Fprintf(progress, "\nNo planned forks left to delete\n")
+			finish(nil)
+			return exitOk
+		}
+
+		// Deleting the planned forks through the same limits, confirmations
+		// and reporting as the forks filtered from a listing
+		reason := "planned for deletion"
+		if decisionsPath != "" {
+			reason = "marked for deletion"
+		}
+		unguardedRepos = planned
+		for _, r := range planned {
+			reasons[repoKey(r)] = reason
+		}
+		return deleteUnguarded()
+	}
+
+	authUser, authErr := fetchAuthenticatedUser(ctx, baseURL, token)
+
+	// Confirming that the token belongs to the owner, unless the owner is an org
+	if confirmOwner {
+		if authErr != nil {
+			fmt.Fprintf(stderr, "Error: could not verify the token owner: %s\n", authErr)
+			return exitCodeFor(ctx, authErr)
+		}
+		if !strings.EqualFold(authUser.Login, owner) {
+			ownerUser, err := fetchUser(ctx, baseURL, owner, token)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: could not verify the token owner: %s\n", err)
+				return exitCodeFor(ctx, err)
+			}
+			if ownerUser.Type != userTypeOrg {
+				if !crossOwner {
+					fmt.Fprintf(
+						stderr,
+						"Error: token belongs to %s, not %s; pass --allow-cross-owner to proceed\n",
+						authUser.Login,
+						owner)
+					return exitAuth
+				}
+				fmt.Fprintf(stderr, "Warning: token belongs to %s, not %s\n", authUser.Login, owner)
+			}
+		}
+	}
+
+	// Listing via /user/repos when the token belongs to the owner and via
+	// /orgs/{owner}/repos for orgs, so that private forks are included
+	endpoint := endpointUsers
+	endpointReason = fmt.Sprintf("the token doesn't belong to %s, public forks only", owner)
+	isTokenOwner := authErr == nil && strings.EqualFold(authUser.Login, owner)
+	var ownerInfo *user // for the summary when it's already been fetched
+	switch {
+	case ownerType == ownerTypeOrg:
+		endpoint = endpointOrgs
+		endpointReason = "owner-type is org, private forks visible to members included"
+	case isTokenOwner:
+		endpoint = endpointUser
+		endpointReason = "the token belongs to the owner, private forks included"
+		ownerInfo = &authUser
+	case authErr != nil:
+		endpointReason = "the authenticated user couldn't be resolved, public forks only"
+		fmt.Fprintf(
+			stderr,
+			"Warning: could not resolve the authenticated user, listing public forks only: %s\n",
+			authErr)
+	case ownerType == ownerTypeAuto:
+		ownerUser, err := fetchUser(ctx, baseURL, owner, token)
+		if err == nil {
+			ownerInfo = &ownerUser
+		}
+		if err == nil && ownerUser.Type == userTypeOrg {
+			endpoint = endpointOrgs
+			endpointReason = "the owner is an org, private forks visible to members included"
+		}
+	}
+	if search != "" {
+		endpointReason = "repos-from-search, forks visible to the token matching the query"
+	}
+	listedFrom = listingPath(owner, endpoint, search != "")
+	if verbose || debug {
+		fmt.Fprintf(progress, "\nListing forks from %s: %s\n", listedFrom, endpointReason)
+	}
+	if visibility != visibilityAll && (endpoint != endpointUser || search != "") {
+		fmt.Fprintf(
+			stderr,
+			"Warning: visibility only applies when listing your own forks, ignoring '%s'\n",
+			visibility)
+	}
 
 	// Fetching repositories
-	fmt.Fprintf(stdout, "\nFetching forked repositories for %s...\n", owner)
-	forkedRepos, err := fetchForkedRepos(
-		ctx,     // ctx
-		baseURL, // baseURL
-		owner,   // owner
-		token,   // token
-		perPage, // perPage
-		maxPage, // maxPage
+	var (
+		forkedRepos []Repo
+		err         error
 	)
+	if search != "" {
+		fmt.Fprintf(progress, "\nSearching forked repositories for %s...\n", owner)
+		forkedRepos, err = searchForkedRepos(
+			ctx,     // ctx
+			baseURL, // baseURL
+			owner,   // owner
+			token,   // token
+			search,  // query
+			perPage, // perPage
+			maxPage, // maxPage
+		)
+	} else {
+		fmt.Fprintf(progress, "\nFetching forked repositories for %s...\n", owner)
+		forkedRepos, err = fetchForkedRepos(
+			ctx,      // ctx
+			baseURL,  // baseURL
+			owner,    // owner
+			token,    // token
+			endpoint, // endpoint
+			perPage,  // perPage
+			maxPage,  // maxPage
+		)
+	}
+
+	// A best-effort listing that skipped pages is reported, and only deleted from
+	// when explicitly allowed since forks on the skipped pages can't be considered
+	var incomplete *incompleteListingError
+	if errors.As(err, &incomplete) {
+		for _, n := range incomplete.pages() {
+			fmt.Fprintf(stderr, "Warning: skipped page %d: %s\n", n, incomplete.failed[n])
+		}
+		fmt.Fprintf(
+			stderr,
+			"Warning: %s, forks on skipped pages aren't considered\n",
+			incomplete)
+		if (delete || transferTo != "") && !allowIncomplete {
+			aborted = "refusing to act on an incomplete listing"
+			finish(nil)
+			fmt.Fprintf(stderr, "Error: %s, pass --allow-incomplete to proceed\n", aborted)
+			return exitPartial
+		}
+		err = nil
+	}
+
+	// A search that didn't list every match is treated the same way, narrowing
+	// the query is the only way to reach the rest
+	var incompleteSearch *incompleteSearchError
+	if errors.As(err, &incompleteSearch) {
+		fmt.Fprintf(stderr, "Warning: %s, narrow the query to consider every fork\n", incompleteSearch)
+		if (delete || transferTo != "") && !allowIncomplete {
+			aborted = "refusing to act on an incomplete listing"
+			finish(nil)
+			fmt.Fprintf(stderr, "Error: %s, pass --allow-incomplete to proceed\n", aborted)
+			return exitPartial
+		}
+		err = nil
+	}
 
 	if err != nil {
 		switch err.Error() {
+		case errSecondaryRateLimit.Error():
+			fmt.Fprintln(stderr, "Error: hit GitHub's secondary rate limit, retry later")
 		case ErrMsg404:
 			fmt.Fprintf(stderr, "Error: user not found\n")
 		case ErrMsg401:
@@ -373,54 +2674,300 @@ func (c *cliConfig) CLI(args []string) int {
 		default:
 			fmt.Fprintf(stderr, "Error: %s\n", err)
 		}
-		return exitErr
+		return exitCodeFor(ctx, err)
+	}
+	if firstPageOnly {
+		fmt.Fprintf(
+			stderr,
+			"Warning: partial scan, only the first page of up to %d forks was fetched\n",
+			perPage)
+	}
+	if len(forkedRepos) > 0 {
+		noun := "forks"
+		if inclSources {
+			noun = "repos"
+		}
+		if len(forkedRepos) == 1 {
+			noun = strings.TrimSuffix(noun, "s")
+		}
+		fmt.Fprintf(progress, "\nFound %d %s", len(forkedRepos), noun)
+		if ownerInfo != nil {
+			fmt.Fprintf(progress, " (%s)", ownerInfo.repoCounts())
+		}
+		fmt.Fprintln(progress)
 	}
 	if len(forkedRepos) == 0 {
-		fmt.Fprintf(stdout, "\nNo forked repositories found\n")
+		// Without the repo scope only public forks are listed, so an empty
+		// listing says nothing about private ones
+		if authErr == nil && authUser.missingScope("repo") {
+			fmt.Fprintf(
+				progress,
+				"\nNo public forks found; private forks require the 'repo' scope\n")
+		} else {
+			fmt.Fprintf(progress, "\nNo forked repositories found\n")
+		}
+		if outputFormat != outputFormatText {
+			rep := newReport(owner, time.Now(), nil, nil, nil, nil)
+			if err := writeOutput(stdout, outputFormat, rep); err != nil {
+				fmt.Fprintf(stderr, "Error: %s\n", err)
+				return exitErr
+			}
+		}
+
+		// Every fork of the previous run is gone, and the empty report written by
+		// finish is what the next run compares against
+		if diffPath != "" {
+			current := newReport(owner, time.Now(), nil, nil, nil, nil)
+			if err := printDiffSince(progress, diffPath, current); err != nil {
+				fmt.Fprintf(stderr, "Error: %s\n", err)
+				return exitErr
+			}
+		}
+		finish(nil)
 		return exitOk
 	}
 
-	// Filtering repositories
-	unguardedRepos, guardedRepos := filterForkedRepos(
-		forkedRepos,
-		protectedRepos,
-		olderThanDays)
+	// Fetching parents for the orphan handling and comparisons that depend on
+	// them, warning about orphaned forks whose parent data is missing
+	if protectOrphans || deleteOrphans || maxAhead >= 0 || emptyOnly || parentOwner != "" ||
+		diffUpstreamURL || protectOthers {
+		if err := fetchParents(ctx, baseURL, token, forkedRepos); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+		for _, r := range forkedRepos {
+			if r.orphaned {
+				fmt.Fprintf(
+					stderr,
+					"Warning: %s has no parent data, its upstream may have been deleted\n",
+					r.URL)
+			}
+		}
+	}
+	if maxAhead >= 0 || emptyOnly || protectOthers {
+		if err := fetchAheadCounts(ctx, baseURL, token, forkedRepos); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+	}
+	if recentBranch {
+		if err := fetchBranchCommits(ctx, baseURL, token, forkedRepos); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+	}
+	if maxBranches >= 0 {
+		if err := fetchBranchCounts(ctx, baseURL, token, forkedRepos); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+	}
+	if protectIfFile != "" {
+		if err := fetchMarkers(ctx, baseURL, token, protectIfFile, forkedRepos); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
+	}
 
-	// Displaying safeguarded repositories
-	fmt.Fprintf(stdout, "\nGuarded forked repos [won't be deleted]:\n")
-	for _, repo := range guardedRepos {
-		fmt.Fprintf(stdout, "    - %s\n", repo.URL)
+	// Filtering repositories, recording the reason for each decision and printing
+	// it as it's made when streaming
+	reasons = make(map[string]string, len(forkedRepos))
+	opts := filterOptions{
+		guardRules:      guardRules,
+		guardFullName:   guardFullName,
+		olderThanDays:   olderThanDays,
+		minIdleDays:     minIdleDays,
+		activityMode:    activityMode,
+		ageBasis:        ageBasis,
+		onlyArchived:    onlyArchived,
+		excludeArchived: exclArchived,
+		protectOrphans:  protectOrphans,
+		deleteOrphans:   deleteOrphans,
+		maxAhead:        maxAhead,
+		keepLatest:      keepLatest,
+		emptyOnly:       emptyOnly,
+		protectOthers:   protectOthers,
+		protectIssues:   protectIssues,
+		minStars:        minStars,
+		maxStars:        maxStars,
+		recentBranch:    recentBranch,
+		maxBranches:     maxBranches,
+		pushedWindow:    pushedWindow,
+		parentOwner:     parentOwner,
+		includeSources:  inclSources,
+		markerFile:      protectIfFile,
+		excludeName:     excludeName,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[repoKey(r)] = reason
+			if !stream {
+				return
+			}
+			decision := decisionDelete
+			if guarded {
+				decision = decisionKeep
+			}
+			fmt.Fprintf(progress, "    %-6s %s (%s)\n", decision, r.Name, reason)
+		},
 	}
+	if stream {
+		fmt.Fprintf(progress, "\nDecisions:\n")
+	}
+	unguardedRepos, guardedRepos = filterForkedRepos(forkedRepos, opts)
+
+	// Displaying the decisions, either as lists or in a machine-readable format,
+	// unless a gh script takes stdout
+	if outputFormat == outputFormatText && ghScriptPath != ghScriptStdout {
+		// Displaying orphaned repositories, whichever way they were decided
+		if protectOrphans || deleteOrphans {
+			fmt.Fprintf(stdout, "\nOrphaned forked repos [upstream deleted]:\n")
+			for _, repo := range slices.Concat(guardedRepos, unguardedRepos) {
+				if repo.orphaned {
+					fmt.Fprintf(stdout, "    - %s\n", repo.URL)
+				}
+			}
+		}
 
-	// Displaying unguarded repositories
-	fmt.Fprintf(stdout, "\nUnguarded forked repos [will be deleted]:\n")
-	for _, repo := range unguardedRepos {
-		fmt.Fprintf(stdout, "    - %s\n", repo.URL)
+		// Showing how far each fork is ahead when that was checked, where to review
+		// its changes, and why guarded forks are kept when the guard rules document it
+		describe := func(r Repo, guarded bool) string {
+			var notes []string
+			if inclSources && !r.IsFork {
+				notes = append(notes, "source")
+			}
+			if (maxAhead >= 0 || emptyOnly || protectOthers) && r.Parent != nil {
+				notes = append(notes, formatAhead(r))
+			}
+			if protectIssues {
+				notes = append(notes, formatOpenIssues(r))
+			}
+			if minStars >= 0 || maxStars >= 0 {
+				notes = append(notes, formatStars(r))
+			}
+			if maxBranches >= 0 && r.branchCount > maxBranches {
+				notes = append(notes, fmt.Sprintf("protected: %d branches", r.branchCount))
+			}
+			if u := compareURL(r); diffUpstreamURL && u != "" {
+				notes = append(notes, "compare "+u)
+			}
+			reason := reasons[repoKey(r)]
+			if guarded && guardFile != "" && !slices.Contains(notes, reason) {
+				notes = append(notes, reason)
+			}
+			if len(notes) == 0 {
+				return r.URL
+			}
+			return fmt.Sprintf("%s (%s)", r.URL, strings.Join(notes, ", "))
+		}
+
+		// Displaying the lists as aligned columns for interactive audits, only on a
+		// terminal so that piped output stays plain
+		if pretty && isTerminal(stdout) {
+			guardedColor, unguardedColor := ansiGreen, ansiRed
+			if _, ok := lookupEnv("NO_COLOR"); ok {
+				guardedColor, unguardedColor = "", ""
+			}
+			now := time.Now()
+			if !hideGuarded {
+				writePrettyGroup(stdout,
+					"Guarded forked repos [won't be deleted]", guardedRepos, now, guardedColor, maxList)
+			}
+			if !hideUnguarded {
+				writePrettyGroup(stdout,
+					"Unguarded forked repos [will be deleted]", unguardedRepos, now, unguardedColor, maxList)
+			}
+		} else {
+			// Displaying safeguarded repositories
+			if !hideGuarded {
+				fmt.Fprintf(stdout, "\nGuarded forked repos [won't be deleted]:\n")
+				shown, more := truncateList(guardedRepos, maxList)
+				for _, repo := range shown {
+					fmt.Fprintf(stdout, "    - %s\n", describe(repo, true))
+				}
+				writeMore(stdout, more)
+			}
+
+			// Displaying unguarded repositories
+			if !hideUnguarded {
+				fmt.Fprintf(stdout, "\nUnguarded forked repos [will be deleted]:\n")
+				shown, more := truncateList(unguardedRepos, maxList)
+				for _, repo := range shown {
+					fmt.Fprintf(stdout, "    - %s\n", describe(repo, false))
+				}
+				writeMore(stdout, more)
+			}
+		}
+	} else if outputFormat != outputFormatText {
+		rep := newReport(owner, time.Now(), guardedRepos, unguardedRepos, nil, reasons)
+		if err := writeOutput(stdout, outputFormat, rep); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
 	}
 
-	// Deleting unguarded repositories
-	if !delete {
-		return exitOk
+	// Comparing against the previous run's report
+	if diffPath != "" {
+		current := newReport(owner, time.Now(), guardedRepos, unguardedRepos, nil, reasons)
+		if err := printDiffSince(progress, diffPath, current); err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitErr
+		}
 	}
 
-	if len(unguardedRepos) == 0 {
-		fmt.Fprintf(stdout, "\nNo unguarded forked repositories to delete\n")
-		return exitOk
+	// Writing the forks that would be deleted as a plan to review and apply later
+	if planPath != "" {
+		if err := writePlan(planPath, newPlan(owner, time.Now(), unguardedRepos)); err != nil {
+			fmt.Fprintf(stderr, "Error: failed to write plan: %s\n", err)
+			return exitErr
+		}
+		fmt.Fprintf(progress, "\nWrote a plan to delete %d forks to %s\n", len(unguardedRepos), planPath)
 	}
 
-	fmt.Fprintf(stdout, "\nDeleting forked repositories...\n")
-	if err := deleteRepos(ctx, baseURL, token, unguardedRepos); err != nil {
-		switch err.Error() {
-		case ErrMsg403:
-			fmt.Fprintf(stderr, "Error: token does not have permission to delete repos\n")
-		case ErrMsg404:
-			fmt.Fprintf(stderr, "Error: repo not found\n")
-		default:
-			fmt.Fprintf(stderr, "Error: %s\n", err)
+	// Writing the deletions as gh commands for running them outside fork-sweeper
+	if ghScriptPath != "" {
+		err := emitGHScript(stdout, ghScriptPath, baseURL, owner, time.Now(), unguardedRepos)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: failed to write gh script: %s\n", err)
+			return exitErr
+		}
+		if ghScriptPath != ghScriptStdout {
+			fmt.Fprintf(progress,
+				"\nWrote a gh script to delete %d forks to %s\n", len(unguardedRepos), ghScriptPath)
+		}
+	}
+
+	// Failing a dry run that was asserted to find nothing to delete
+	if assertEmpty && len(unguardedRepos) > 0 {
+		finish(nil)
+		fmt.Fprintf(stderr, "Error: expected no forks to delete, found %d:\n", len(unguardedRepos))
+		for _, repo := range unguardedRepos {
+			fmt.Fprintf(stderr, "    - %s\n", repo.URL)
+		}
+		return exitDrift
+	}
+
+	// Detaching a fork from its network has no API on GitHub, GHES or Gitea, it
+	// only works from the repo's settings or through GitHub Support. Nothing is
+	// changed, the forks to detach are listed with where to do it instead.
+	if detach {
+		finish(nil)
+		if len(unguardedRepos) == 0 {
+			fmt.Fprintf(progress, "\nNo unguarded forked repositories to detach\n")
+			return exitOk
+		}
+		fmt.Fprintln(stderr,
+			"Error: detach is not supported on this host, its API can't detach a fork from its network")
+		fmt.Fprintf(stderr, "Detach the %d unguarded forks from their settings instead:\n", len(unguardedRepos))
+		for _, repo := range unguardedRepos {
+			fmt.Fprintf(stderr, "    - %s/settings\n", repo.URL)
 		}
 		return exitErr
 	}
 
-	fmt.Fprintf(stdout, "\nForks deleted successfully\n")
-	return exitOk
+	// Stopping at the listing unless deleting, or transferring
+	if !delete && transferTo == "" {
+		finish(nil)
+		return exitOk
+	}
+
+	return deleteUnguarded()
 }
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -22,6 +27,7 @@ func TestUnmarshalRepo(t *testing.T) {
 		"name": "test-repo",
 		"html_url": "https://github.com/test-owner/test-repo",
 		"fork": false,
+		"archived": true,
 		"owner": {
 			"login": "test-owner"
 		},
@@ -31,10 +37,11 @@ func TestUnmarshalRepo(t *testing.T) {
 	}`
 
 	// Expected repo object based on the JSON string
-	expected := repo{
-		Name:   "test-repo",
-		URL:    "https://github.com/test-owner/test-repo",
-		IsFork: false,
+	expected := Repo{
+		Name:     "test-repo",
+		URL:      "https://github.com/test-owner/test-repo",
+		IsFork:   false,
+		Archived: true,
 		Owner: struct {
 			Name string `json:"login"`
 		}{
@@ -46,7 +53,7 @@ func TestUnmarshalRepo(t *testing.T) {
 	}
 
 	// Unmarshal the JSON string into a repo struct
-	var result repo
+	var result Repo
 	err := json.Unmarshal([]byte(jsonStr), &result)
 	if err != nil {
 		t.Fatalf("Unmarshalling failed: %v", err)
@@ -61,6 +68,166 @@ func TestUnmarshalRepo(t *testing.T) {
 }
 
 // TestFetchForkedReposPage with adjusted repo struct
+func TestFetchForkedRepos_StartPage(t *testing.T) {
+	t.Parallel()
+	var pagesMu sync.Mutex
+	var pages []string
+	mockServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page := r.URL.Query().Get("page")
+			pagesMu.Lock()
+			pages = append(pages, page)
+			pagesMu.Unlock()
+			fmt.Fprintf(w, `[{"name": "repo-on-page-%s", "fork": true}]`, page)
+		}))
+	defer mockServer.Close()
+
+	ctx := withRequestConfig(context.Background(), requestConfig{startPage: 3})
+	forkedRepos, err := fetchForkedRepos(
+		ctx,            // ctx
+		mockServer.URL, // baseURL
+		"test-owner",   // owner
+		"test-token",   // token
+		endpointUsers,  // endpoint
+		1,              // perPage
+		4,              // maxPage
+	)
+	if err != nil {
+		t.Fatalf("fetchForkedRepos() failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(pages, []string{"3", "4"}) {
+		t.Errorf("Expected pages 3 and 4 to be fetched, got %v", pages)
+	}
+	if len(forkedRepos) != 2 || forkedRepos[0].Name != "repo-on-page-3" {
+		t.Errorf("Expected the repos of pages 3 and 4, got %v", forkedRepos)
+	}
+}
+
+func TestFetchForkedRepos_PageOfSources(t *testing.T) {
+	t.Parallel()
+	// Sources fill page 1 of /user/repos, which filters forks client-side
+	mockServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("page") {
+			case "1":
+				fmt.Fprintln(w, `[{"name": "source-1"}, {"name": "source-2"}]`)
+			case "2":
+				fmt.Fprintln(w, `[{"name": "fork-on-page-2", "fork": true}]`)
+			default:
+				fmt.Fprintln(w, `[]`)
+			}
+		}))
+	defer mockServer.Close()
+
+	for _, endpoint := range []string{endpointUser, endpointUsers} {
+		forkedRepos, err := fetchForkedRepos(
+			context.Background(), // ctx
+			mockServer.URL,       // baseURL
+			"test-owner",         // owner
+			"test-token",         // token
+			endpoint,             // endpoint
+			2,                    // perPage
+			10,                   // maxPage
+		)
+		if err != nil {
+			t.Fatalf("fetchForkedRepos() failed: %v", err)
+		}
+		if len(forkedRepos) != 1 || forkedRepos[0].Name != "fork-on-page-2" {
+			t.Errorf("Expected the fork on page 2 listed from %s, got %v", endpoint, forkedRepos)
+		}
+	}
+}
+
+func TestFetchForkedRepos_BestEffort(t *testing.T) {
+	t.Parallel()
+	mockServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch page := r.URL.Query().Get("page"); page {
+			case "2":
+				w.WriteHeader(http.StatusBadGateway)
+			case "4":
+				fmt.Fprintln(w, `[]`)
+			default:
+				fmt.Fprintf(w, `[{"name": "repo-on-page-%s", "fork": true}]`, page)
+			}
+		}))
+	defer mockServer.Close()
+
+	fetch := func(bestEffort bool) ([]Repo, error) {
+		ctx := withRequestConfig(context.Background(), requestConfig{bestEffort: bestEffort})
+		return fetchForkedRepos(
+			ctx,            // ctx
+			mockServer.URL, // baseURL
+			"test-owner",   // owner
+			"test-token",   // token
+			endpointUsers,  // endpoint
+			1,              // perPage
+			10,             // maxPage
+		)
+	}
+
+	if _, err := fetch(false); err == nil || err.Error() != "API request failed with status: 502" {
+		t.Errorf("Expected the failed page to abort the listing, got %v", err)
+	}
+
+	forkedRepos, err := fetch(true)
+	var incomplete *incompleteListingError
+	if !errors.As(err, &incomplete) || !reflect.DeepEqual(incomplete.pages(), []int{2}) {
+		t.Fatalf("Expected page 2 to be reported as skipped, got %v", err)
+	}
+	if len(forkedRepos) != 2 || forkedRepos[1].Name != "repo-on-page-3" {
+		t.Errorf("Expected the repos of pages 1 and 3, got %v", forkedRepos)
+	}
+}
+
+func TestCLI_BestEffort(t *testing.T) {
+	t.Parallel()
+	forks := []Repo{newMockFork("old-fork", time.Now().AddDate(-1, 0, 0))}
+	fetch := func(ctx context.Context, baseURL, owner, token, endpoint string, perPage, maxPage int) ([]Repo, error) {
+		return forks, &incompleteListingError{failed: map[int]error{2: errors.New("boom")}}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+	}{
+		{"list", []string{"--best-effort"}, exitOk},
+		{"delete refused", []string{"--best-effort", "--delete"}, exitPartial},
+		{"delete allowed", []string{"--best-effort", "--allow-incomplete", "--delete"}, exitOk},
+		{"allow without best effort", []string{"--allow-incomplete"}, exitErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			var deleted []Repo
+			cliConfig := NewCLIConfig(stdout, stderr, "test-version").
+				withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+				withFetchForkedRepos(fetch).
+				withDeleteRepos(func(ctx context.Context, baseURL, token string, repos []Repo) (deleteResult, error) {
+					deleted = append(deleted, repos...)
+					return deleteResult{}, nil
+				}).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := append([]string{"--owner", "testOwner", "--token", "testToken", "--yes"}, tt.args...)
+			if exitCode := cliConfig.CLI(args); exitCode != tt.exitCode {
+				t.Fatalf("Expected exit code %d, got %d: %s", tt.exitCode, exitCode, stderr)
+			}
+			if tt.exitCode == exitErr {
+				return
+			}
+			if !strings.Contains(stderr.String(), "Warning: skipped page 2: boom") {
+				t.Errorf("Expected a warning about the skipped page, got %q", stderr)
+			}
+			if wantDeleted := tt.name == "delete allowed"; (len(deleted) == 1) != wantDeleted {
+				t.Errorf("Expected deletion %v, deleted %v", wantDeleted, deleted)
+			}
+		})
+	}
+}
+
 func TestFetchForkedReposPage(t *testing.T) {
 	t.Parallel()
 	mockServer := httptest.NewServer(
@@ -79,7 +246,7 @@ func TestFetchForkedReposPage(t *testing.T) {
 		}))
 	defer mockServer.Close()
 
-	expected := []repo{
+	expected := []Repo{
 		{
 			Name:   "test-forked-repo",
 			URL:    "https://github.com/test-owner/test-forked-repo",
@@ -93,11 +260,12 @@ func TestFetchForkedReposPage(t *testing.T) {
 		},
 	}
 
-	forkedRepos, err := fetchForkedReposPage(
+	forkedRepos, _, err := fetchForkedReposPage(
 		context.Background(), // ctx
 		mockServer.URL,       // baseURL
 		"test-owner",         // owner
 		"test-token",         // token
+		endpointUsers,        // endpoint
 		1,                    // pageNum
 		10,                   // perPage
 	)
@@ -121,6 +289,112 @@ func TestFetchForkedReposPage(t *testing.T) {
 	}
 }
 
+func TestFetchForkedReposPage_NoForkFilter(t *testing.T) {
+	t.Parallel()
+	mockServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Like hosts that filter on type=forks but leave out the fork field
+			fmt.Fprintln(w, `[{"name": "repo-1"}, {"name": "repo-2", "fork": false}]`)
+		}))
+	defer mockServer.Close()
+
+	for _, noForkFilter := range []bool{false, true} {
+		ctx := withRequestConfig(context.Background(), requestConfig{noForkFilter: noForkFilter})
+		repos, _, err := fetchForkedReposPage(ctx, mockServer.URL, "test-owner", "test-token", endpointUsers, 1, 10)
+		if err != nil {
+			t.Fatalf("fetchForkedReposPage returned an error: %v", err)
+		}
+
+		want := 0
+		if noForkFilter {
+			want = 2
+		}
+		if len(repos) != want {
+			t.Fatalf("Expected %d repos with noForkFilter %v, got %d", want, noForkFilter, len(repos))
+		}
+		for _, r := range repos {
+			if !r.IsFork {
+				t.Errorf("Expected %s to be marked as a fork", r.Name)
+			}
+		}
+	}
+}
+
+func TestForkListURL(t *testing.T) {
+	t.Parallel()
+	gitea := requestConfig{perPageParam: "limit", omitTypeParam: true}
+
+	tests := []struct {
+		endpoint string
+		cfg      requestConfig
+		expected string
+	}{
+		{
+			endpointUsers,
+			requestConfigFrom(context.Background()),
+			"https://api.test/users/test-owner/repos?type=forks&page=2&per_page=10",
+		},
+		{
+			endpointUser,
+			requestConfigFrom(context.Background()),
+			"https://api.test/user/repos?affiliation=owner&page=2&per_page=10",
+		},
+		{
+			endpointOrgs,
+			requestConfigFrom(context.Background()),
+			"https://api.test/orgs/test-owner/repos?type=forks&page=2&per_page=10",
+		},
+		{
+			endpointUsers,
+			gitea,
+			"https://api.test/users/test-owner/repos?page=2&limit=10",
+		},
+		{
+			endpointUser,
+			gitea,
+			"https://api.test/user/repos?affiliation=owner&page=2&limit=10",
+		},
+		{
+			endpointOrgs,
+			requestConfig{perPageParam: "per_page", includeSources: true},
+			"https://api.test/orgs/test-owner/repos?page=2&per_page=10",
+		},
+		{
+			endpointUser,
+			requestConfig{perPageParam: "per_page", visibility: visibilityPrivate},
+			"https://api.test/user/repos?affiliation=owner&page=2&per_page=10&visibility=private",
+		},
+		{
+			endpointUsers,
+			requestConfig{perPageParam: "per_page", visibility: visibilityPrivate},
+			"https://api.test/users/test-owner/repos?type=forks&page=2&per_page=10",
+		},
+		{
+			endpointUser,
+			requestConfig{
+				perPageParam: "per_page",
+				visibility:   visibilityPublic,
+				sort:         apiSortPushed,
+				direction:    apiDirectionDesc,
+			},
+			"https://api.test/user/repos?affiliation=owner&page=2&per_page=10&visibility=public" +
+				"&sort=pushed&direction=desc",
+		},
+		{
+			endpointOrgs,
+			requestConfig{perPageParam: "per_page", sort: apiSortFullName},
+			"https://api.test/orgs/test-owner/repos?type=forks&page=2&per_page=10&sort=full_name",
+		},
+	}
+
+	for _, tt := range tests {
+		got := forkListURL("https://api.test", "test-owner", tt.endpoint, 2, 10, tt.cfg)
+		if got != tt.expected {
+			t.Errorf("forkListURL(%q) = %q, want %q", tt.endpoint, got, tt.expected)
+		}
+	}
+}
+
 func TestFetchForkedRepos(t *testing.T) {
 	t.Parallel()
 	mockServer := httptest.NewServer(
@@ -148,7 +422,7 @@ func TestFetchForkedRepos(t *testing.T) {
 
 	defer mockServer.Close()
 
-	expected := []repo{
+	expected := []Repo{
 		{
 			Name:   "test-repo-1",
 			URL:    "https://test.com/test-owner/test-repo-1",
@@ -178,6 +452,7 @@ func TestFetchForkedRepos(t *testing.T) {
 		mockServer.URL,       // baseURL
 		"test-owner",         // owner
 		"test-token",         // token
+		endpointUsers,        // endpoint
 		10,                   // perPage
 		1,                    // maxPage
 	)
@@ -267,9 +542,164 @@ func TestDoRequest(t *testing.T) {
 		})
 	}
 }
+func TestDoRequest_Headers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		cfg            *requestConfig
+		wantAccept     string
+		wantAPIVersion string
+	}{
+		{
+			name:           "default headers",
+			wantAccept:     defaultAccept,
+			wantAPIVersion: defaultAPIVersion,
+		},
+		{
+			name: "overridden headers",
+			cfg: &requestConfig{
+				accept:     "application/vnd.github.mercy-preview+json",
+				apiVersion: "2030-01-01",
+			},
+			wantAccept:     "application/vnd.github.mercy-preview+json",
+			wantAPIVersion: "2030-01-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if got := r.Header.Get("Accept"); got != tt.wantAccept {
+						t.Errorf("Expected Accept %q, got %q", tt.wantAccept, got)
+					}
+					if got := r.Header.Get("X-GitHub-Api-Version"); got != tt.wantAPIVersion {
+						t.Errorf("Expected X-GitHub-Api-Version %q, got %q", tt.wantAPIVersion, got)
+					}
+				}))
+			defer server.Close()
+
+			ctx := context.Background()
+			if tt.cfg != nil {
+				ctx = withRequestConfig(ctx, *tt.cfg)
+			}
+			req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+			if err := doRequest(req, "test-token", nil); err != nil {
+				t.Errorf("doRequest() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestUser_RepoCounts(t *testing.T) {
+	t.Parallel()
+	private := 3
+	tests := []struct {
+		u        user
+		expected string
+	}{
+		{user{Login: "test-owner", PublicRepos: 12}, "test-owner has 12 public repos"},
+		{
+			user{Login: "test-owner", PublicRepos: 12, PrivateRepos: &private},
+			"test-owner has 12 public and 3 private repos",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.u.repoCounts(); got != tt.expected {
+			t.Errorf("repoCounts() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestUser_MissingScope(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{"fine-grained token", http.Header{}, false},
+		{"repo scope", http.Header{"X-Oauth-Scopes": {"repo, user"}}, false},
+		{"public_repo scope", http.Header{"X-Oauth-Scopes": {"public_repo, user"}}, true},
+		{"no scopes", http.Header{"X-Oauth-Scopes": {""}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u user
+			u.readHeader(tt.header)
+			if got := u.missingScope("repo"); got != tt.want {
+				t.Errorf("missingScope(%q) = %v, want %v", "repo", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUser_MissingScopes(t *testing.T) {
+	t.Parallel()
+	u := user{scopes: []string{"repo", "admin:org"}}
+	got := u.missingScopes([]string{"delete_repo", "public_repo", "read:org", "repo", "workflow"})
+	expected := []string{"delete_repo", "workflow"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("missingScopes() = %v, want %v", got, expected)
+	}
+}
+
+func TestCLI_RequireScope(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		scopes   []string
+		wantExit int
+		wantErr  string
+	}{
+		{"granted", []string{"repo", "delete_repo"}, exitOk, ""},
+		{"missing", []string{"public_repo"}, exitAuth, "missing the required scopes: repo, delete_repo"},
+		{"fine-grained token", nil, exitAuth, "doesn't report its scopes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			var deleteCalled bool
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withFetchAuthenticatedUser(func(ctx context.Context, baseURL, token string) (user, error) {
+				return user{Login: "testOwner", scopes: tt.scopes}, nil
+			}).
+				withFetchForkedRepos(mockFetchForkedRepos).
+				withDeleteRepos(func(ctx context.Context, baseURL, token string, repos []Repo) (deleteResult, error) {
+					deleteCalled = true
+					return deleteResult{}, nil
+				}).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{
+				"--owner", "testOwner", "--token", "testToken", "--delete",
+				"--require-scope", "repo", "--require-scope", "delete_repo",
+			}
+			if exitCode := cliConfig.CLI(args); exitCode != tt.wantExit {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.wantExit, exitCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("Expected %q in stderr, got %q", tt.wantErr, stderr.String())
+			}
+			if tt.wantExit != exitOk && deleteCalled {
+				t.Error("Expected nothing to be deleted")
+			}
+		})
+	}
+}
+
 func TestFilterForkedRepos_EmptyInput(t *testing.T) {
 	t.Parallel()
-	unguarded, guarded := filterForkedRepos(nil, nil, 30)
+	unguarded, guarded := filterForkedRepos(nil, filterOptions{olderThanDays: 30})
 	if len(unguarded) != 0 || len(guarded) != 0 {
 		t.Errorf("Expected both slices to be empty, got %v and %v", unguarded, guarded)
 	}
@@ -277,19 +707,22 @@ func TestFilterForkedRepos_EmptyInput(t *testing.T) {
 
 func TestFilterForkedRepos_AllGuarded(t *testing.T) {
 	now := time.Now()
-	forkedRepos := []repo{
+	forkedRepos := []Repo{
 		{Name: "test-repo-1", CreatedAt: now, UpdatedAt: now, PushedAt: now},
 		{Name: "test-repo-2", CreatedAt: now, UpdatedAt: now, PushedAt: now},
 	}
 	guardedRepoNames := []string{"test-repo"}
-	unguarded, guarded := filterForkedRepos(forkedRepos, guardedRepoNames, 30)
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames(guardedRepoNames),
+		olderThanDays: 30,
+	})
 	if len(unguarded) != 0 || len(guarded) != 2 {
 		t.Errorf("Expected unguarded 0 and guarded 2, got unguarded %d and guarded %d", len(unguarded), len(guarded))
 	}
 }
 
 func TestFilterForkedRepos_AllUnguardedDueToDate(t *testing.T) {
-	forkedRepos := []repo{
+	forkedRepos := []Repo{
 		{
 			Name:      "old-repo-1",
 			CreatedAt: time.Now().AddDate(0, -1, 0),
@@ -302,7 +735,10 @@ func TestFilterForkedRepos_AllUnguardedDueToDate(t *testing.T) {
 			PushedAt:  time.Now().AddDate(0, -2, 0)},
 	}
 	var guardedRepoNames []string
-	unguarded, guarded := filterForkedRepos(forkedRepos, guardedRepoNames, 10)
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames(guardedRepoNames),
+		olderThanDays: 10,
+	})
 
 	if len(unguarded) != 2 || len(guarded) != 0 {
 		t.Errorf("Expected unguarded 2 and guarded 0, got unguarded %d and guarded %d", len(unguarded), len(guarded))
@@ -310,7 +746,7 @@ func TestFilterForkedRepos_AllUnguardedDueToDate(t *testing.T) {
 }
 
 func TestFilterForkedRepos_UnknownGuardRepoName(t *testing.T) {
-	forkedRepos := []repo{
+	forkedRepos := []Repo{
 		{
 			Name:      "old-repo-1",
 			CreatedAt: time.Now().AddDate(0, -1, 0),
@@ -324,7 +760,10 @@ func TestFilterForkedRepos_UnknownGuardRepoName(t *testing.T) {
 	}
 	guardedRepoNames := []string{"unknown-repo-1", "unknown-repo-2"}
 
-	unguarded, guarded := filterForkedRepos(forkedRepos, guardedRepoNames, 10)
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames(guardedRepoNames),
+		olderThanDays: 10,
+	})
 
 	if len(unguarded) != 2 || len(guarded) != 0 {
 		t.Errorf("Expected unguarded 2 and guarded 0, got unguarded %d and guarded %d", len(unguarded), len(guarded))
@@ -332,7 +771,7 @@ func TestFilterForkedRepos_UnknownGuardRepoName(t *testing.T) {
 }
 
 func TestFilterForkedRepos_MixedGuardedUnguarded(t *testing.T) {
-	forkedRepos := []repo{
+	forkedRepos := []Repo{
 		{
 			Name:      "new-repo-1",
 			CreatedAt: time.Now(),
@@ -346,14 +785,17 @@ func TestFilterForkedRepos_MixedGuardedUnguarded(t *testing.T) {
 	}
 
 	guardedRepoNames := []string{"protected"}
-	unguarded, guarded := filterForkedRepos(forkedRepos, guardedRepoNames, 30)
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames(guardedRepoNames),
+		olderThanDays: 30,
+	})
 	if len(unguarded) != 0 || len(guarded) != 2 {
 		t.Errorf("Expected unguarded 0 and guarded 2, got unguarded %d and guarded %d", len(unguarded), len(guarded))
 	}
 }
 
 func TestFilterForkedRepos_CaseInsensitive(t *testing.T) {
-	forkedRepos := []repo{
+	forkedRepos := []Repo{
 		{
 			Name:      "Case-Sensitive-Repo",
 			CreatedAt: time.Now(),
@@ -361,14 +803,17 @@ func TestFilterForkedRepos_CaseInsensitive(t *testing.T) {
 			PushedAt:  time.Now()},
 	}
 	guardedRepoNames := []string{"case-sensitive"}
-	unguarded, guarded := filterForkedRepos(forkedRepos, guardedRepoNames, 30)
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames(guardedRepoNames),
+		olderThanDays: 30,
+	})
 	if len(unguarded) != 0 || len(guarded) != 1 {
 		t.Errorf("Expected unguarded 0 and guarded 1, got unguarded %d and guarded %d", len(unguarded), len(guarded))
 	}
 }
 
 func TestFilterForkedRepos_MultipleMatches(t *testing.T) {
-	forkedRepos := []repo{
+	forkedRepos := []Repo{
 		{
 			Name:      "match-1",
 			CreatedAt: time.Now().AddDate(0, -1, 0),
@@ -382,167 +827,1785 @@ func TestFilterForkedRepos_MultipleMatches(t *testing.T) {
 	}
 	guardedRepoNames := []string{"match-1", "match-2"}
 
-	unguarded, guarded := filterForkedRepos(forkedRepos, guardedRepoNames, 29)
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames(guardedRepoNames),
+		olderThanDays: 29,
+	})
 	if len(unguarded) != 0 || len(guarded) != 2 {
 		t.Errorf("Expected unguarded 0 and guarded 2, got unguarded %d and guarded %d", len(unguarded), len(guarded))
 	}
 }
 
-func TestDeleteRepo(t *testing.T) {
+func TestFilterForkedRepos_ActivityMode(t *testing.T) {
 	t.Parallel()
-	// Setup a local HTTP test server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodDelete {
-			t.Errorf("Expected DELETE method, got %s", r.Method)
-		}
-		// Respond with an empty JSON object to simulate a successful deletion
-		fmt.Fprintln(w, "{}")
-	}))
-	defer server.Close()
+	recent := time.Now()
+	old := time.Now().AddDate(0, -2, 0)
 
-	// Test the deleteRepo function
-	ctx := context.Background()
-	baseURL := server.URL // Use the test server URL
-	owner := "testOwner"
-	repoName := "testRepo"
-	token := "testToken"
+	tests := []struct {
+		name         string
+		createdAt    time.Time
+		pushedAt     time.Time
+		activityMode string
+		wantGuarded  bool
+	}{
+		{"any: both recent", recent, recent, activityModeAny, true},
+		{"any: created recent", recent, old, activityModeAny, true},
+		{"any: pushed recent", old, recent, activityModeAny, true},
+		{"any: both old", old, old, activityModeAny, false},
+		{"all: both recent", recent, recent, activityModeAll, true},
+		{"all: created recent", recent, old, activityModeAll, false},
+		{"all: pushed recent", old, recent, activityModeAll, false},
+		{"all: both old", old, old, activityModeAll, false},
+	}
 
-	err := deleteRepo(ctx, baseURL, owner, repoName, token)
-	if err != nil {
-		t.Errorf("deleteRepo() failed: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forkedRepos := []Repo{
+				{
+					Name:      "test-repo",
+					CreatedAt: tt.createdAt,
+					UpdatedAt: tt.pushedAt,
+					PushedAt:  tt.pushedAt},
+			}
+			_, guarded := filterForkedRepos(forkedRepos, filterOptions{
+				olderThanDays: 30,
+				activityMode:  tt.activityMode,
+			})
+			if gotGuarded := len(guarded) == 1; gotGuarded != tt.wantGuarded {
+				t.Errorf("Expected guarded %v, got %v", tt.wantGuarded, gotGuarded)
+			}
+		})
 	}
 }
 
-func TestDeleteRepos(t *testing.T) {
+func TestFilterForkedRepos_AgeBasis(t *testing.T) {
 	t.Parallel()
-	server := httptest.NewServer(
-		http.HandlerFunc(
-			func(w http.ResponseWriter, r *http.Request) {
-				// You can add more logic here to verify the request path, method, etc.
-				fmt.Fprintln(w, "{}") // Simulate success
-			}))
-	defer server.Close()
+	recent := time.Now()
+	old := time.Now().AddDate(0, -2, 0)
 
-	ctx := context.Background()
-	baseURL := server.URL // Use the test server URL for the baseURL
-	token := "testToken"
-	repos := []repo{
-		{Name: "testOwner/testRepo1", URL: ""},
-		{Name: "testOwner/testRepo2", URL: ""},
+	tests := []struct {
+		name        string
+		createdAt   time.Time
+		pushedAt    time.Time
+		ageBasis    string
+		wantGuarded bool
+	}{
+		{"activity: created recent", recent, old, ageBasisActivity, true},
+		{"activity: both old", old, old, ageBasisActivity, false},
+		{"changes: created recent", recent, old, ageBasisChanges, false},
+		{"changes: pushed recent", old, recent, ageBasisChanges, true},
+		{"changes: both old", old, old, ageBasisChanges, false},
 	}
 
-	err := deleteRepos(ctx, baseURL, token, repos)
-	if err != nil {
-		t.Errorf("deleteRepos() failed: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forkedRepos := []Repo{
+				{
+					Name:      "test-repo",
+					CreatedAt: tt.createdAt,
+					UpdatedAt: tt.pushedAt,
+					PushedAt:  tt.pushedAt},
+			}
+			_, guarded := filterForkedRepos(forkedRepos, filterOptions{
+				olderThanDays: 30,
+				ageBasis:      tt.ageBasis,
+			})
+			if gotGuarded := len(guarded) == 1; gotGuarded != tt.wantGuarded {
+				t.Errorf("Expected guarded %v, got %v", tt.wantGuarded, gotGuarded)
+			}
+		})
 	}
 }
 
-// Test cli flow
-
-// Mock functions to replace actual behavior in tests
-var (
-	mockFlagErrorHandler = flag.ContinueOnError
-
-	mockFetchForkedRepos = func(
-		ctx context.Context,
-		baseURL,
-		owner,
-		token string,
-		perPage,
-		maxPage int) ([]repo, error) {
-		fmt.Println("mockFetchForkedRepos")
-		return []repo{{Name: "test-repo"}}, nil
-	}
+func TestFilterForkedRepos_MinIdleDays(t *testing.T) {
+	t.Parallel()
+	recent := time.Now()
+	old := time.Now().Add(-60 * 24 * time.Hour)
 
-	mockFilterForkedRepos = func(
-		forkedRepos []repo,
-		guardedRepoNames []string,
-		olderThanDays int) ([]repo, []repo) {
-		fmt.Println("mockFilterForkedRepos")
-		return forkedRepos, nil
+	tests := []struct {
+		name        string
+		updatedAt   time.Time
+		pushedAt    time.Time
+		wantGuarded bool
+		wantReason  string
+	}{
+		{"updated recent, pushed old", recent, old, false, "last pushed 60d ago"},
+		{"pushed recent", old, recent, true, "last pushed today"},
+		{"never pushed", recent, time.Time{}, false, "never pushed"},
 	}
 
-	mockDeleteRepos = func(
-		ctx context.Context,
-		baseURL,
-		token string,
-		repos []repo) error {
-		fmt.Println("mockDeleteRepos")
-		return nil
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forkedRepos := []Repo{
+				{Name: "test-repo", CreatedAt: recent, UpdatedAt: tt.updatedAt, PushedAt: tt.pushedAt},
+			}
+			var reason string
+			_, guarded := filterForkedRepos(forkedRepos, filterOptions{
+				olderThanDays: 30,
+				minIdleDays:   30,
+				onDecision: func(r Repo, guarded bool, got string) {
+					reason = got
+				},
+			})
+			if gotGuarded := len(guarded) == 1; gotGuarded != tt.wantGuarded {
+				t.Errorf("Expected guarded %v, got %v", tt.wantGuarded, gotGuarded)
+			}
+			if reason != tt.wantReason {
+				t.Errorf("Expected reason %q, got %q", tt.wantReason, reason)
+			}
+		})
 	}
-)
+}
 
-func TestNewCLIConfig_Defaults(t *testing.T) {
+func TestRepoWithoutCreated(t *testing.T) {
 	t.Parallel()
-	config := NewCLIConfig(nil, nil, "")
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	pushed := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
 
-	if config.fetchForkedRepos == nil ||
-		config.deleteRepos == nil ||
-		config.flagErrorHandling != flag.ExitOnError {
-		t.Fatal("Default functions were not set correctly")
+	r := Repo{CreatedAt: created, UpdatedAt: updated, PushedAt: pushed}.withoutCreated()
+	if got := r.lastActivity(); !got.Equal(updated) {
+		t.Errorf("Expected last activity %v, got %v", updated, got)
+	}
+	if got := r.firstActivity(); !got.Equal(pushed) {
+		t.Errorf("Expected first activity %v, got %v", pushed, got)
 	}
 }
 
-func TestWithFlagErrorHandling_Option(t *testing.T) {
+func TestRepoLastActivity(t *testing.T) {
 	t.Parallel()
-	config := NewCLIConfig(nil, nil, "").withFlagErrorHandling(mockFlagErrorHandler)
-	if config.flagErrorHandling != mockFlagErrorHandler {
-		t.Fatal("WithFlagErrorHandling did not set the flag error handling")
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	pushed := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	r := Repo{CreatedAt: created, UpdatedAt: updated, PushedAt: pushed}
+	if got := r.lastActivity(); !got.Equal(updated) {
+		t.Errorf("Expected last activity %v, got %v", updated, got)
 	}
 }
 
-func TestWithFetchForkedRepos_Option(t *testing.T) {
+func TestRepoFirstActivity(t *testing.T) {
 	t.Parallel()
-	config := NewCLIConfig(nil, nil, "").withFetchForkedRepos(mockFetchForkedRepos)
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	pushed := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
 
-	if config.fetchForkedRepos == nil {
-		t.Fatal("WithFetchForkedRepos did not set the function")
+	r := Repo{CreatedAt: created, UpdatedAt: updated, PushedAt: pushed}
+	if got := r.firstActivity(); !got.Equal(pushed) {
+		t.Errorf("Expected first activity %v, got %v", pushed, got)
+	}
+}
+
+func TestLimitPerOwner(t *testing.T) {
+	t.Parallel()
+	repos := []Repo{
+		newTestRepo("repo-1"), newTestRepo("repo-2"), newTestRepo("repo-3"), newTestRepo("repo-4"),
+	}
+	repos[1].Owner.Name = "other-owner"
+	repos[3].Owner.Name = "Test-Owner" // owners are compared case-insensitively
+
+	kept, skipped := limitPerOwner(repos, 1)
+
+	var got []string
+	for _, r := range kept {
+		got = append(got, r.Name)
+	}
+	if expected := []string{"repo-1", "repo-2"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected kept %v, got %v", expected, got)
+	}
+	if expected := map[string]int{"test-owner": 2}; !reflect.DeepEqual(skipped, expected) {
+		t.Errorf("Expected skipped %v, got %v", expected, skipped)
+	}
+}
+
+func TestCLI_PerOwnerLimit(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("stale-1", old), newMockFork("stale-2", old), newMockFork("stale-3", old),
+	})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--per-owner-limit", "2", "--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 2 {
+		t.Errorf("Expected 2 forks to be deleted, got %v", *deleted)
+	}
+	want := "Skipping 1 fork of testOwner over the per-owner-limit of 2"
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("Expected %q in output, got %q", want, stdout.String())
+	}
+}
+
+func TestCLI_MaxList(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("stale-1", old), newMockFork("stale-2", old), newMockFork("stale-3", old),
+	})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--max-list", "1", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 3 {
+		t.Errorf("Expected all 3 forks to be deleted, got %v", *deleted)
+	}
+	output := stdout.String()
+	if !strings.Contains(output, "stale-1\n    ... and 2 more\n") || strings.Contains(output, "stale-2\n") {
+		t.Errorf("Expected the unguarded list to be truncated, got %q", output)
+	}
+}
+
+func TestCLI_HideLists(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		flag        string
+		wantShown   string
+		wantDropped string
+	}{
+		{"--hide-guarded", "Unguarded forked repos", "Guarded forked repos"},
+		{"--hide-unguarded", "Guarded forked repos", "Unguarded forked repos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			server, _ := newMockGitHubServer(t, []Repo{
+				newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0)),
+				newMockFork("active-repo", time.Now()),
+			})
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{"--owner", "testOwner", "--token", "testToken", tt.flag}
+			if exitCode := cliConfig.CLI(args); exitCode != 0 {
+				t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+			}
+
+			output := stdout.String()
+			if !strings.Contains(output, "\n"+tt.wantShown) || strings.Contains(output, "\n"+tt.wantDropped) {
+				t.Errorf("Expected only %q to be listed, got %q", tt.wantShown, output)
+			}
+		})
+	}
+}
+
+func TestSortReposByActivity(t *testing.T) {
+	t.Parallel()
+	newRepo := func(name string, year int) Repo {
+		ts := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return Repo{Name: name, CreatedAt: ts, UpdatedAt: ts, PushedAt: ts}
+	}
+
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{deleteOrderOldest, []string{"repo-2019", "repo-2020", "repo-2021"}},
+		{deleteOrderNewest, []string{"repo-2021", "repo-2020", "repo-2019"}},
+	}
+
+	for _, tt := range tests {
+		repos := []Repo{
+			newRepo("repo-2020", 2020),
+			newRepo("repo-2021", 2021),
+			newRepo("repo-2019", 2019),
+		}
+		sortReposByActivity(repos, tt.order)
+
+		var got []string
+		for _, r := range repos {
+			got = append(got, r.Name)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("sortReposByActivity(%q) = %v, want %v", tt.order, got, tt.expected)
+		}
+	}
+}
+
+func TestFilterForkedRepos_LastActivityBoundary(t *testing.T) {
+	t.Parallel()
+	cutOff := time.Now().AddDate(0, 0, -30)
+	old := time.Now().AddDate(-1, 0, 0)
+
+	tests := []struct {
+		name        string
+		r           Repo
+		wantGuarded bool
+	}{
+		{
+			name:        "last activity just after cutoff",
+			r:           Repo{CreatedAt: old, UpdatedAt: old, PushedAt: cutOff.Add(time.Minute)},
+			wantGuarded: true,
+		},
+		{
+			name:        "last activity just before cutoff",
+			r:           Repo{CreatedAt: old, UpdatedAt: old, PushedAt: cutOff.Add(-time.Minute)},
+			wantGuarded: false,
+		},
+		{
+			name:        "only the update is recent",
+			r:           Repo{CreatedAt: old, UpdatedAt: cutOff.Add(time.Minute), PushedAt: old},
+			wantGuarded: true,
+		},
+		{
+			name:        "only the creation is recent",
+			r:           Repo{CreatedAt: cutOff.Add(time.Minute), UpdatedAt: old, PushedAt: old},
+			wantGuarded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, guarded := filterForkedRepos([]Repo{tt.r}, filterOptions{olderThanDays: 30})
+			if gotGuarded := len(guarded) == 1; gotGuarded != tt.wantGuarded {
+				t.Errorf("Expected guarded %v, got %v", tt.wantGuarded, gotGuarded)
+			}
+		})
+	}
+}
+
+func TestFilterForkedRepos_Archived(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "archived-repo", Archived: true, CreatedAt: old, UpdatedAt: old, PushedAt: old},
+		{Name: "live-repo", CreatedAt: old, UpdatedAt: old, PushedAt: old},
+	}
+
+	tests := []struct {
+		name          string
+		opts          filterOptions
+		wantUnguarded []string
+	}{
+		{"no archive filter", filterOptions{}, []string{"archived-repo", "live-repo"}},
+		{"only archived", filterOptions{onlyArchived: true}, []string{"archived-repo"}},
+		{"exclude archived", filterOptions{excludeArchived: true}, []string{"live-repo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.olderThanDays = 30
+			unguarded, _ := filterForkedRepos(forkedRepos, tt.opts)
+
+			var got []string
+			for _, r := range unguarded {
+				got = append(got, r.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantUnguarded) {
+				t.Errorf("Expected unguarded %v, got %v", tt.wantUnguarded, got)
+			}
+		})
+	}
+}
+
+func TestFilterForkedRepos_ProtectOrphans(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "orphaned-repo", orphaned: true, CreatedAt: old, UpdatedAt: old, PushedAt: old},
+		{Name: "parented-repo", CreatedAt: old, UpdatedAt: old, PushedAt: old},
+	}
+
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{olderThanDays: 30})
+	if len(unguarded) != 2 {
+		t.Errorf("Expected orphans to be deletion candidates by default, got %v", unguarded)
+	}
+
+	unguarded, guarded := filterForkedRepos(
+		forkedRepos, filterOptions{olderThanDays: 30, protectOrphans: true})
+	if len(unguarded) != 1 || len(guarded) != 1 || guarded[0].Name != "orphaned-repo" {
+		t.Errorf("Expected the orphan to be guarded, got %v and %v", unguarded, guarded)
+	}
+}
+
+func TestFilterForkedRepos_DeleteOrphans(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	forkedRepos := []Repo{
+		{Name: "orphaned-repo", orphaned: true, CreatedAt: now, UpdatedAt: now, PushedAt: now},
+		{Name: "guarded-orphan", orphaned: true, CreatedAt: now, UpdatedAt: now, PushedAt: now},
+		{Name: "parented-repo", CreatedAt: now, UpdatedAt: now, PushedAt: now},
+	}
+
+	reasons := map[string]string{}
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames([]string{"guarded"}),
+		olderThanDays: 30,
+		deleteOrphans: true,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "orphaned-repo" {
+		t.Errorf("Expected the active orphan to be selected, got %v", unguarded)
+	}
+	if len(guarded) != 2 {
+		t.Errorf("Expected the guarded orphan and parented repo to be kept, got %v", guarded)
+	}
+	if reasons["orphaned-repo"] != "orphaned fork" {
+		t.Errorf("Expected reason 'orphaned fork', got %q", reasons["orphaned-repo"])
+	}
+}
+
+func TestFilterForkedRepos_ExcludeName(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "dotfiles", CreatedAt: old, UpdatedAt: old, PushedAt: old},
+		{Name: "dotfiles-work", CreatedAt: time.Now(), UpdatedAt: time.Now(), PushedAt: time.Now()},
+		{Name: "my-dotfiles", CreatedAt: old, UpdatedAt: old, PushedAt: old},
+	}
+
+	var decided []string
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		excludeName:   regexp.MustCompile("^dotfiles"),
+		onDecision: func(r Repo, guarded bool, reason string) {
+			decided = append(decided, r.Name)
+		},
+	})
+
+	if len(guarded) != 0 || len(unguarded) != 1 || unguarded[0].Name != "my-dotfiles" {
+		t.Errorf("Expected only my-dotfiles to remain, got %v and %v", unguarded, guarded)
+	}
+	if !reflect.DeepEqual(decided, []string{"my-dotfiles"}) {
+		t.Errorf("Expected no decisions for excluded forks, got %v", decided)
+	}
+}
+
+func TestFilterForkedRepos_KeepLatest(t *testing.T) {
+	t.Parallel()
+	var forkedRepos []Repo
+	for _, days := range []int{300, 100, 200, 10, 400} {
+		ts := time.Now().AddDate(0, 0, -days)
+		forkedRepos = append(forkedRepos, Repo{
+			Name:      fmt.Sprintf("repo-%d", days),
+			CreatedAt: ts,
+			UpdatedAt: ts,
+			PushedAt:  ts,
+		})
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		keepLatest:    3,
+		excludeName:   regexp.MustCompile("^repo-100$"),
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	var got []string
+	for _, r := range unguarded {
+		got = append(got, r.Name)
+	}
+	if expected := []string{"repo-400"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected unguarded %v, got %v", expected, got)
+	}
+
+	expected := map[string]string{
+		"repo-10":  "last active 10d ago",
+		"repo-200": "among the 3 most recently active",
+		"repo-300": "among the 3 most recently active",
+		"repo-400": "last active 400d ago",
+	}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("Expected reasons %v, got %v", expected, reasons)
+	}
+}
+
+func TestFilterForkedRepos_OnDecision(t *testing.T) {
+	t.Parallel()
+	forkedRepos := []Repo{
+		{
+			Name:      "active-repo",
+			CreatedAt: time.Now().AddDate(0, 0, -3),
+			UpdatedAt: time.Now().AddDate(0, 0, -3),
+			PushedAt:  time.Now().AddDate(0, 0, -3)},
+		{
+			Name:      "old-repo",
+			CreatedAt: time.Now().AddDate(0, 0, -400),
+			UpdatedAt: time.Now().AddDate(0, 0, -400),
+			PushedAt:  time.Now().AddDate(0, 0, -400)},
+		{
+			Name:      "protected-repo",
+			CreatedAt: time.Now().AddDate(0, 0, -400),
+			UpdatedAt: time.Now().AddDate(0, 0, -400),
+			PushedAt:  time.Now().AddDate(0, 0, -400)},
+	}
+
+	var decisions []string
+	filterForkedRepos(forkedRepos, filterOptions{
+		guardRules:    guardRulesFromNames([]string{"protected"}),
+		olderThanDays: 30,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			decisions = append(decisions, fmt.Sprintf("%s %v %s", r.Name, guarded, reason))
+		},
+	})
+
+	expected := []string{
+		"active-repo true last active 3d ago",
+		"old-repo false last active 400d ago",
+		"protected-repo true guarded by 'protected'",
+	}
+	if !reflect.DeepEqual(decisions, expected) {
+		t.Errorf("Expected decisions %v, got %v", expected, decisions)
+	}
+}
+
+func TestDeleteRepo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		responseStatus int
+		responseBody   string
+	}{
+		{"200 with empty object", http.StatusOK, "{}"},
+		{"202 accepted", http.StatusAccepted, ""},
+		{"204 no content", http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup a local HTTP test server
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Errorf("Expected DELETE method, got %s", r.Method)
+				}
+				w.WriteHeader(tt.responseStatus)
+				fmt.Fprint(w, tt.responseBody)
+			}))
+			defer server.Close()
+
+			// Test the deleteRepo function
+			ctx := context.Background()
+			baseURL := server.URL // Use the test server URL
+			owner := "testOwner"
+			repoName := "testRepo"
+			token := "testToken"
+
+			err := deleteRepo(ctx, baseURL, owner, repoName, token)
+			if err != nil {
+				t.Errorf("deleteRepo() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteRepo_PathPrefix(t *testing.T) {
+	t.Parallel()
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The prefix is kept whether or not the base URL ends with a slash
+	for _, baseURL := range []string{server.URL + "/api/v3", server.URL + "/api/v3/"} {
+		err := deleteRepo(context.Background(), baseURL, "testOwner", "testRepo", "testToken")
+		if err != nil {
+			t.Fatalf("deleteRepo() failed: %v", err)
+		}
+		if gotPath != "/api/v3/repos/testOwner/testRepo" {
+			t.Errorf("Expected the prefixed delete path with base %q, got %q", baseURL, gotPath)
+		}
+	}
+}
+
+func TestDeleteExitCode(t *testing.T) {
+	t.Parallel()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		succeeded int
+		expected  int
+	}{
+		{"interrupted", cancelled, context.Canceled, 2, exitInterrupted},
+		{"token rejected", context.Background(), errors.New(ErrMsg401), 0, exitAuth},
+		{"no permission", context.Background(), errors.New(ErrMsg403), 0, exitAuth},
+		{"denied after deleting some", context.Background(), errors.New(ErrMsg403), 1, exitPartial},
+		{"not found", context.Background(), errors.New(ErrMsg404), 0, exitPartial},
+	}
+
+	for _, tt := range tests {
+		if got := deleteExitCode(tt.ctx, tt.err, tt.succeeded); got != tt.expected {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{errInvalidToken, exitAuth},
+		{fmt.Errorf("verifying o/r: %w", errors.New(ErrMsg401)), exitAuth},
+		{errors.New(ErrMsg404), exitErr},
+	}
+
+	for _, tt := range tests {
+		if got := exitCodeFor(ctx, tt.err); got != tt.expected {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestDoRequest_EmptyBody(t *testing.T) {
+	t.Parallel()
+	for _, status := range []int{http.StatusAccepted, http.StatusNoContent} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		var result map[string]any
+		if err := doRequest(req, "test-token", &result); err != nil {
+			t.Errorf("doRequest() with status %d failed: %v", status, err)
+		}
+		server.Close()
+	}
+
+	// A 200 OK is expected to carry JSON, an empty one fails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	var result map[string]any
+	if err := doRequest(req, "test-token", &result); !errors.Is(err, errEmptyBody) {
+		t.Errorf("Expected errEmptyBody for an empty 200, got %v", err)
+	}
+}
+
+func TestDoRequest_ErrorBodyLog(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"message": "Validation Failed"}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		limit    int64
+		expected string
+	}{
+		{0, `failed with status 422 (client request ID test-id): {"message": "Validation Failed"}` + "\n"}, // the default limit
+		{11, `failed with status 422 (client request ID test-id): {"message":...(truncated)` + "\n"},
+	}
+
+	for _, tt := range tests {
+		var log bytes.Buffer
+		ctx := withRequestConfig(context.Background(), requestConfig{
+			errorBodyLog:    &log,
+			maxBodyLogBytes: tt.limit,
+		})
+		req, _ := http.NewRequestWithContext(ctx, "DELETE", server.URL+"/repos/o/r", nil)
+		req.Header.Set(clientRequestIDHeader, "test-id")
+
+		err := doRequest(req, "test-token", nil)
+		if err == nil || err.Error() != "API request failed with status: 422: Validation Failed" {
+			t.Errorf("Expected the validation error, got %v", err)
+		}
+		if !strings.HasPrefix(log.String(), "DELETE "+server.URL+"/repos/o/r ") ||
+			!strings.HasSuffix(log.String(), tt.expected) {
+			t.Errorf("Expected the body logged with limit %d, got %q", tt.limit, log.String())
+		}
+	}
+}
+
+func TestDoRequest_RequestID(t *testing.T) {
+	t.Parallel()
+	var clientIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIDs = append(clientIDs, r.Header.Get(clientRequestIDHeader))
+		w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	var errorLog, requestLog bytes.Buffer
+	ctx := withRequestConfig(context.Background(), requestConfig{
+		errorBodyLog: &errorLog,
+		requestLog:   &requestLog,
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", server.URL+"/repos/o/r", nil)
+	if err := doRequest(req, "test-token", nil); err == nil {
+		t.Fatal("Expected the DELETE to fail")
+	}
+	req, _ = http.NewRequestWithContext(ctx, "GET", server.URL+"/repos/o/r", nil)
+	if err := doRequest(req, "test-token", &Repo{}); err != nil {
+		t.Fatalf("Expected the GET to succeed, got %v", err)
+	}
+
+	if len(clientIDs) != 2 || len(clientIDs[0]) != 36 || clientIDs[0] == clientIDs[1] {
+		t.Fatalf("Expected a distinct client request ID per request, got %q", clientIDs)
+	}
+	expected := "failed with status 403 (request ID ABCD:1234, client request ID " + clientIDs[0] + ")"
+	if !strings.Contains(errorLog.String(), expected) {
+		t.Errorf("Expected the failure logged with its request IDs, got %q", errorLog.String())
+	}
+	expected = "GET " + server.URL + "/repos/o/r returned status 200 " +
+		"(request ID ABCD:1234, client request ID " + clientIDs[1] + ")\n"
+	if requestLog.String() != expected {
+		t.Errorf("Expected the success logged as %q, got %q", expected, requestLog.String())
+	}
+}
+
+func TestDoRequest_ClientRequestIDRetried(t *testing.T) {
+	t.Parallel()
+	var clientIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIDs = append(clientIDs, r.Header.Get(clientRequestIDHeader))
+		if len(clientIDs) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	var requestLog bytes.Buffer
+	ctx := withRequestConfig(context.Background(), requestConfig{
+		retries:    1,
+		retryDelay: time.Millisecond,
+		budget:     newRetryBudget(1),
+		requestLog: &requestLog,
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", server.URL+"/repos/o/r", nil)
+	if err := doRequest(req, "test-token", nil); err != nil {
+		t.Fatalf("Expected the retried DELETE to succeed, got %v", err)
+	}
+
+	if len(clientIDs) != 2 || clientIDs[0] == "" || clientIDs[0] != clientIDs[1] {
+		t.Fatalf("Expected the retry to reuse the client request ID, got %q", clientIDs)
+	}
+	expected := "DELETE " + server.URL + "/repos/o/r attempt 1 returned status 502, " +
+		"retrying (client request ID " + clientIDs[0] + ")\n"
+	if !strings.HasPrefix(requestLog.String(), expected) {
+		t.Errorf("Expected the retry logged as %q, got %q", expected, requestLog.String())
+	}
+}
+
+func TestDoRequest_MaxResponseBytes(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"name": "%s"}]`, strings.Repeat("a", 1000))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		limit   int64
+		wantErr bool
+	}{
+		{0, false}, // the default limit
+		{2000, false},
+		{500, true},
+	}
+
+	for _, tt := range tests {
+		ctx := withRequestConfig(context.Background(), requestConfig{maxResponseBytes: tt.limit})
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+		var result []Repo
+		err := doRequest(req, "test-token", &result)
+		if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "exceeded 500 bytes")) {
+			t.Errorf("Expected a size error with limit %d, got %v", tt.limit, err)
+		}
+		if !tt.wantErr && (err != nil || len(result) != 1) {
+			t.Errorf("doRequest() with limit %d failed: %v", tt.limit, err)
+		}
+	}
+}
+
+func TestDeleteRepos(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				// You can add more logic here to verify the request path, method, etc.
+				fmt.Fprintln(w, "{}") // Simulate success
+			}))
+	defer server.Close()
+
+	ctx := context.Background()
+	baseURL := server.URL // Use the test server URL for the baseURL
+	token := "testToken"
+	repos := []Repo{
+		{Name: "testOwner/testRepo1", URL: ""},
+		{Name: "testOwner/testRepo2", URL: ""},
+	}
+
+	result, err := deleteRepos(ctx, baseURL, token, repos)
+	if err != nil {
+		t.Errorf("deleteRepos() failed: %v", err)
+	}
+	if len(result.timings) != len(repos) {
+		t.Errorf("Expected %d timings, got %d", len(repos), len(result.timings))
+	}
+	if !reflect.DeepEqual(result.succeeded, repos) || len(result.failed) != 0 {
+		t.Errorf("Expected every repo to succeed, got %+v", result)
+	}
+}
+
+func TestDeleteRepos_FailFast(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/forbidden-repo") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			// Holding the other deletions long enough for the failure to cancel them
+			select {
+			case <-r.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("forbidden-repo"), newTestRepo("slow-repo-1"), newTestRepo("slow-repo-2")}
+	for _, failFast := range []bool{false, true} {
+		ctx := withRequestConfig(context.Background(), requestConfig{failFast: failFast})
+		result, err := deleteRepos(ctx, server.URL, "test-token", repos)
+		if err == nil || err.Error() != ErrMsg403 {
+			t.Fatalf("Expected the 403 to be returned, got %v", err)
+		}
+		if len(result.failed) == 0 || result.failed[0].repo.Name != "forbidden-repo" {
+			t.Errorf("Expected forbidden-repo to be the first failure, got %+v", result.failed)
+		}
+
+		cancelled := result.cancelled()
+		if expected := map[bool]int{false: 0, true: 2}[failFast]; cancelled != expected {
+			t.Errorf("Expected %d cancelled deletions with fail-fast %v, got %d", expected, failFast, cancelled)
+		}
+	}
+}
+
+func TestDeleteRepos_PartialResult(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/forbidden-repo") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("repo-1"), newTestRepo("forbidden-repo"), newTestRepo("repo-2")}
+	result, err := deleteRepos(context.Background(), server.URL, "test-token", repos)
+	if err == nil {
+		t.Fatal("Expected the 403 to be returned")
+	}
+	if !reflect.DeepEqual(result.succeeded, []Repo{repos[0], repos[2]}) {
+		t.Errorf("Expected repo-1 and repo-2 to succeed, got %v", result.succeeded)
+	}
+	if len(result.failed) != 1 || result.failed[0].repo.Name != "forbidden-repo" {
+		t.Fatalf("Expected only forbidden-repo to fail, got %+v", result.failed)
+	}
+
+	w := new(bytes.Buffer)
+	printFailedDeletions(w, result.failed)
+	expected := "\nFailed deletions:\n    - test-owner/forbidden-repo: " + ErrMsg403 + "\n"
+	if w.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, w.String())
+	}
+}
+
+func TestCLI_FailFastAndContinueOnError(t *testing.T) {
+	t.Parallel()
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+		withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--fail-fast", "--continue-on-error",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d", exitErr, exitCode)
+	}
+	if !strings.Contains(stderr.String(), "fail-fast and continue-on-error are mutually exclusive") {
+		t.Errorf("Expected error message not found in output: %q", stderr)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+	var durations []time.Duration
+	for i := 1; i <= 20; i++ {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p        int
+		expected time.Duration
+	}{
+		{50, 10 * time.Millisecond},
+		{95, 19 * time.Millisecond},
+		{100, 20 * time.Millisecond},
+		{0, 1 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(durations, tt.p); got != tt.expected {
+			t.Errorf("percentile(%d) = %v, want %v", tt.p, got, tt.expected)
+		}
+	}
+
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of no durations = %v, want 0", got)
+	}
+}
+
+func TestPrintDeleteTimings(t *testing.T) {
+	t.Parallel()
+	timings := []deleteTiming{
+		{repo: Repo{URL: "https://github.com/o/fast"}, duration: 10 * time.Millisecond},
+		{repo: Repo{URL: "https://github.com/o/slow"}, duration: 300 * time.Millisecond},
+		{repo: Repo{URL: "https://github.com/o/medium"}, duration: 50 * time.Millisecond},
+	}
+
+	w := new(bytes.Buffer)
+	printDeleteTimings(w, timings, 2)
+	output := w.String()
+
+	if !strings.Contains(output, "p50 50ms, p95 300ms") {
+		t.Errorf("Expected percentiles in output, got %q", output)
+	}
+	if !strings.Contains(output, "slow (300ms)\n    - https://github.com/o/medium (50ms)") {
+		t.Errorf("Expected slowest deletions in order, got %q", output)
+	}
+	if strings.Contains(output, "fast") {
+		t.Errorf("Expected only the 2 slowest deletions, got %q", output)
+	}
+}
+
+// Test cli flow
+
+// Mock functions to replace actual behavior in tests
+var (
+	mockFlagErrorHandler = flag.ContinueOnError
+
+	mockFetchAuthenticatedUser = func(
+		ctx context.Context,
+		baseURL,
+		token string) (user, error) {
+		return user{Login: "testOwner", Type: "User"}, nil
+	}
+
+	mockFetchUser = func(
+		ctx context.Context,
+		baseURL,
+		owner,
+		token string) (user, error) {
+		if owner == "testOrg" {
+			return user{Login: owner, Type: userTypeOrg}, nil
+		}
+		return user{Login: owner, Type: "User"}, nil
+	}
+
+	mockFetchForkedRepos = func(
+		ctx context.Context,
+		baseURL,
+		owner,
+		token,
+		endpoint string,
+		perPage,
+		maxPage int) ([]Repo, error) {
+		fmt.Println("mockFetchForkedRepos")
+		return []Repo{{Name: "test-repo"}}, nil
+	}
+
+	mockFilterForkedRepos = func(forkedRepos []Repo, opts filterOptions) ([]Repo, []Repo) {
+		fmt.Println("mockFilterForkedRepos")
+		return forkedRepos, nil
+	}
+
+	mockDeleteRepos = func(
+		ctx context.Context,
+		baseURL,
+		token string,
+		repos []Repo) (deleteResult, error) {
+		fmt.Println("mockDeleteRepos")
+		return deleteResult{}, nil
+	}
+)
+
+func TestNewCLIConfig_Defaults(t *testing.T) {
+	t.Parallel()
+	config := NewCLIConfig(nil, nil, "")
+
+	if config.fetchForkedRepos == nil ||
+		config.deleteRepos == nil ||
+		config.flagErrorHandling != flag.ExitOnError {
+		t.Fatal("Default functions were not set correctly")
+	}
+}
+
+func TestNewCLIConfig_DefaultFilterForkedRepos(t *testing.T) {
+	t.Parallel()
+	config := NewCLIConfig(nil, nil, "")
+
+	if config.filterForkedRepos == nil {
+		t.Fatal("Default filterForkedRepos was not set")
+	}
+}
+
+func TestWithFlagErrorHandling_Option(t *testing.T) {
+	t.Parallel()
+	config := NewCLIConfig(nil, nil, "").withFlagErrorHandling(mockFlagErrorHandler)
+	if config.flagErrorHandling != mockFlagErrorHandler {
+		t.Fatal("WithFlagErrorHandling did not set the flag error handling")
+	}
+}
+
+func TestWithFetchForkedRepos_Option(t *testing.T) {
+	t.Parallel()
+	config := NewCLIConfig(nil, nil, "").withFetchForkedRepos(mockFetchForkedRepos)
+
+	if config.fetchForkedRepos == nil {
+		t.Fatal("WithFetchForkedRepos did not set the function")
 	}
 }
 
 func TestWithFilterForkedRepos_Option(t *testing.T) {
 	t.Parallel()
-	config := NewCLIConfig(nil, nil, "").withFilterForkedRepos(filterForkedRepos)
-	if config.filterForkedRepos == nil {
-		t.Fatal("WithFilterForkedRepos did not set the function")
+	config := NewCLIConfig(nil, nil, "").withFilterForkedRepos(filterForkedRepos)
+	if config.filterForkedRepos == nil {
+		t.Fatal("WithFilterForkedRepos did not set the function")
+	}
+}
+
+func TestWithDeleteRepos_Option(t *testing.T) {
+	t.Parallel()
+	config := NewCLIConfig(nil, nil, "").withDeleteRepos(mockDeleteRepos)
+	if config.deleteRepos == nil {
+		t.Fatal("WithDeleteRepos did not set the function")
+	}
+}
+
+func TestFlagEnvName(t *testing.T) {
+	t.Parallel()
+	if got := flagEnvName("older-than-days"); got != "FORK_SWEEPER_OLDER_THAN_DAYS" {
+		t.Errorf("flagEnvName() = %q, want %q", got, "FORK_SWEEPER_OLDER_THAN_DAYS")
+	}
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{
+		"FORK_SWEEPER_OWNER":           "envOwner",
+		"FORK_SWEEPER_OLDER_THAN_DAYS": "30",
+		"FORK_SWEEPER_DELETE":          "true",
+		"FORK_SWEEPER_GUARD":           "foo, bar",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	var (
+		owner         string
+		olderThanDays int
+		delete        bool
+		guard         stringSlice
+	)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&owner, "owner", "", "")
+	fs.IntVar(&olderThanDays, "older-than-days", 60, "")
+	fs.BoolVar(&delete, "delete", false, "")
+	fs.Var(&guard, "guard", "")
+
+	if err := setFlagsFromEnv(fs, lookupEnv); err != nil {
+		t.Fatalf("setFlagsFromEnv() failed: %v", err)
+	}
+
+	if owner != "envOwner" || olderThanDays != 30 || !delete {
+		t.Errorf("Unexpected flag values: %q, %d, %v", owner, olderThanDays, delete)
+	}
+	if !reflect.DeepEqual([]string(guard), []string{"foo", "bar"}) {
+		t.Errorf("Expected guard [foo bar], got %v", guard)
+	}
+
+	env["FORK_SWEEPER_OLDER_THAN_DAYS"] = "thirty"
+	err := setFlagsFromEnv(fs, lookupEnv)
+	if err == nil || !strings.Contains(err.Error(), "FORK_SWEEPER_OLDER_THAN_DAYS") {
+		t.Errorf("Expected error naming the variable, got %v", err)
+	}
+}
+
+func TestCLI_FlagsFromEnv(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	var gotOwner, gotToken string
+	var gotGuards []string
+
+	env := map[string]string{
+		"FORK_SWEEPER_OWNER": "envOwner",
+		"FORK_SWEEPER_TOKEN": "envToken",
+		"FORK_SWEEPER_GUARD": "env-guard",
+	}
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withLookupEnv(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}).
+		withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchUser(mockFetchUser).
+		withFetchForkedRepos(func(
+			ctx context.Context,
+			baseURL,
+			owner,
+			token,
+			endpoint string,
+			perPage,
+			maxPage int) ([]Repo, error) {
+			gotOwner, gotToken = owner, token
+			return []Repo{newTestRepo("repo-1")}, nil
+		}).
+		withFilterForkedRepos(func(forkedRepos []Repo, opts filterOptions) ([]Repo, []Repo) {
+			gotGuards = nil
+			for _, r := range opts.guardRules {
+				gotGuards = append(gotGuards, r.Pattern)
+			}
+			return forkedRepos, nil
+		}).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	// The command line flag takes precedence over the environment
+	exitCode := cliConfig.CLI([]string{"--owner", "flagOwner"})
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if gotOwner != "flagOwner" || gotToken != "envToken" {
+		t.Errorf("Expected owner flagOwner and token envToken, got %s and %s", gotOwner, gotToken)
+	}
+	if !reflect.DeepEqual(gotGuards, []string{"env-guard"}) {
+		t.Errorf("Expected the guard from the environment, got %v", gotGuards)
+	}
+
+	// A repeatable flag on the command line replaces the environment's values
+	// instead of adding to them
+	exitCode = cliConfig.CLI([]string{"--guard", "flag-guard"})
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if !reflect.DeepEqual(gotGuards, []string{"flag-guard"}) {
+		t.Errorf("Expected only the guard from the command line, got %v", gotGuards)
+	}
+}
+
+func TestCLI_MissingOwnerToken(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler).
+		withFilterForkedRepos(mockFilterForkedRepos)
+
+		// Execute the CLI
+	exitCode := cliConfig.CLI([]string{"cmd"})
+
+	if !strings.Contains(stderr.String(), "owner and token are required") {
+		t.Errorf("Expected error message not found in output")
+	}
+
+	if exitCode != 1 {
+		t.Errorf("Expected os.Exit to be called once, got %d", exitCode)
+	}
+}
+
+func TestCLI_InvalidActivityMode(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler).
+		withFilterForkedRepos(mockFilterForkedRepos)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--activity-mode", "some"}
+	exitCode := cliConfig.CLI(args)
+
+	if !strings.Contains(stderr.String(), "activity-mode must be 'any' or 'all'") {
+		t.Errorf("Expected error message not found in output")
+	}
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+}
+
+func TestCLI_InvalidAgeBasis(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler).
+		withFilterForkedRepos(mockFilterForkedRepos)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--age-basis", "account-age"}
+	exitCode := cliConfig.CLI(args)
+
+	if !strings.Contains(stderr.String(), "age-basis must be 'activity' or 'changes'") {
+		t.Errorf("Expected error message not found in output")
+	}
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+}
+
+func TestCLI_InvalidAPIOrder(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"--api-sort", "stars"}, "api-sort must be 'created', 'updated', 'pushed' or 'full_name'"},
+		{[]string{"--api-direction", "up"}, "api-direction must be 'asc' or 'desc'"},
+	}
+
+	for _, tt := range tests {
+		stderr := new(bytes.Buffer)
+		cliConfig := NewCLIConfig(
+			new(bytes.Buffer),
+			stderr,
+			"test-version",
+		).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+			withFetchForkedRepos(mockFetchForkedRepos).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args := append([]string{"--owner", "testOwner", "--token", "testToken"}, tt.args...)
+		if exitCode := cliConfig.CLI(args); exitCode != 1 {
+			t.Errorf("Expected exit code 1 for %v, got %d", tt.args, exitCode)
+		}
+		if !strings.Contains(stderr.String(), tt.wantErr) {
+			t.Errorf("Expected %q, got %q", tt.wantErr, stderr.String())
+		}
+	}
+}
+
+func TestCLI_PerPage(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		args     []string
+		exitCode int
+		stderr   string
+	}{
+		{[]string{"--per-page", "0"}, exitErr, "Error: per-page must be positive, got 0"},
+		{[]string{"--per-page", "30"}, exitOk, "Warning: per-page 30 takes more requests than GitHub's maximum of 100"},
+		{[]string{"--per-page", "30", "--per-page-param", "limit"}, exitOk, ""},
+		{nil, exitOk, ""},
+	}
+
+	for _, tt := range tests {
+		stderr := new(bytes.Buffer)
+		cliConfig := NewCLIConfig(
+			new(bytes.Buffer),
+			stderr,
+			"test-version",
+		).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+			withFetchForkedRepos(mockFetchForkedRepos).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args := append([]string{"--owner", "testOwner", "--token", "testToken"}, tt.args...)
+		if exitCode := cliConfig.CLI(args); exitCode != tt.exitCode {
+			t.Errorf("Expected exit code %d for %v, got %d", tt.exitCode, tt.args, exitCode)
+		}
+		if tt.stderr != "" && !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("Expected %q for %v, got %q", tt.stderr, tt.args, stderr.String())
+		}
+		if tt.stderr == "" && strings.Contains(stderr.String(), "per-page") {
+			t.Errorf("Expected no per-page warning for %v, got %q", tt.args, stderr.String())
+		}
+	}
+}
+
+func TestCLI_InvalidVisibility(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler).
+		withFilterForkedRepos(mockFilterForkedRepos)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--visibility", "internal"}
+	exitCode := cliConfig.CLI(args)
+
+	if !strings.Contains(stderr.String(), "visibility must be 'all', 'public' or 'private'") {
+		t.Errorf("Expected error message not found in output")
+	}
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+}
+
+func TestCLI_InvalidNameRegexExclude(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--name-regex-exclude", "("}
+	exitCode := cliConfig.CLI(args)
+
+	if !strings.Contains(stderr.String(), "invalid name-regex-exclude") {
+		t.Errorf("Expected error message not found in output")
+	}
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+}
+
+func TestCLI_Success(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withDeleteRepos(mockDeleteRepos).
+		withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler).
+		withFilterForkedRepos(mockFilterForkedRepos)
+
+	// Execute the CLI
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--older-than-days", "30"}
+
+	exitCode := cliConfig.CLI(args)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+}
+
+func TestCLI_IncludeSources(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	source := newMockFork("source-repo", old)
+	source.IsFork = false
+	server, _ := newMockGitHubServer(t, []Repo{newMockFork("stale-repo", old), source})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--include-sources"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	expected := "Guarded forked repos [won't be deleted]:\n" +
+		"    - https://github.com/testOwner/source-repo (source)\n"
+	if !strings.Contains(stdout.String(), expected) {
+		t.Errorf("Expected the source to be guarded, got %q", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "Found 2 repos") {
+		t.Errorf("Expected a repo count, got %q", stdout.String())
+	}
+}
+
+func TestCLI_IncludeSourcesReadOnly(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--include-sources", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "include-sources is read-only") {
+		t.Errorf("Expected a read-only error, got %q", stderr.String())
+	}
+}
+
+func TestCLI_DryRunAssertEmpty(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		forks    []Repo
+		extra    []string
+		wantExit int
+		wantErr  string
+	}{
+		{
+			"nothing to delete",
+			[]Repo{newMockFork("active-repo", time.Now())},
+			nil,
+			exitOk,
+			"",
+		},
+		{
+			"forks to delete",
+			[]Repo{newMockFork("active-repo", time.Now()), newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))},
+			nil,
+			exitDrift,
+			"expected no forks to delete, found 1:\n    - https://github.com/testOwner/stale-repo\n",
+		},
+		{
+			"with delete",
+			nil,
+			[]string{"--delete"},
+			exitErr,
+			"dry-run-assert-empty is read-only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, deleted := newMockGitHubServer(t, tt.forks)
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := append(
+				[]string{"--owner", "testOwner", "--token", "testToken", "--dry-run-assert-empty"}, tt.extra...)
+			if exitCode := cliConfig.CLI(args); exitCode != tt.wantExit {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.wantExit, exitCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("Expected %q in stderr, got %q", tt.wantErr, stderr.String())
+			}
+			if len(*deleted) != 0 {
+				t.Errorf("Expected nothing to be deleted, got %v", *deleted)
+			}
+		})
+	}
+}
+
+func TestCLI_FirstPageOnly(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	var gotMaxPage int
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(func(
+			ctx context.Context,
+			baseURL,
+			owner,
+			token,
+			endpoint string,
+			perPage,
+			maxPage int) ([]Repo, error) {
+			gotMaxPage = maxPage
+			return mockFetchForkedRepos(ctx, baseURL, owner, token, endpoint, perPage, maxPage)
+		}).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--max-page", "5", "--first-page-only",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+
+	if gotMaxPage != 1 {
+		t.Errorf("Expected to fetch 1 page, got %d", gotMaxPage)
+	}
+	if !strings.Contains(stderr.String(), "partial scan") {
+		t.Errorf("Expected a partial scan warning, got %q", stderr.String())
+	}
+}
+
+func TestCLI_MaxDelete(t *testing.T) {
+	t.Parallel()
+	twoRepos := []Repo{{Name: "test-repo-1"}, {Name: "test-repo-2"}}
+
+	tests := []struct {
+		name         string
+		repos        []Repo
+		args         []string
+		wantExitCode int
+		wantDeleted  bool
+	}{
+		{
+			name:         "within limit",
+			repos:        twoRepos,
+			args:         []string{"--delete", "--max-delete", "2"},
+			wantExitCode: 0,
+			wantDeleted:  true,
+		},
+		{
+			name:         "exceeds limit",
+			repos:        twoRepos,
+			args:         []string{"--delete", "--max-delete", "1"},
+			wantExitCode: 1,
+			wantDeleted:  false,
+		},
+		{
+			name:         "exceeds limit with yes",
+			repos:        twoRepos,
+			args:         []string{"--delete", "--max-delete", "1", "--yes"},
+			wantExitCode: 0,
+			wantDeleted:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			deleted := false
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+				withFetchForkedRepos(func(
+					ctx context.Context,
+					baseURL,
+					owner,
+					token,
+					endpoint string,
+					perPage,
+					maxPage int) ([]Repo, error) {
+					return tt.repos, nil
+				}).
+				withDeleteRepos(func(
+					ctx context.Context,
+					baseURL,
+					token string,
+					repos []Repo) (deleteResult, error) {
+					deleted = true
+					return deleteResult{}, nil
+				}).
+				withFlagErrorHandling(mockFlagErrorHandler).
+				withFilterForkedRepos(mockFilterForkedRepos)
+
+			args := append([]string{"--owner", "testOwner", "--token", "testToken"}, tt.args...)
+			exitCode := cliConfig.CLI(args)
+
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantExitCode, exitCode)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("Expected deleted %v, got %v", tt.wantDeleted, deleted)
+			}
+			if !tt.wantDeleted && !strings.Contains(stderr.String(), "refusing to delete 2 forks") {
+				t.Errorf("Expected max-delete error, got %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestCLI_MaxDeleteRecorded(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("repo-1", old), newMockFork("repo-2", old)})
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.json")
+	summaryPath := filepath.Join(dir, "summary.json")
+
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		new(bytes.Buffer),
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner",
+		"--token", "testToken",
+		"--delete",
+		"--max-delete", "1",
+		"--report", reportPath,
+		"--summary-json-to", summaryPath,
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitErr, exitCode, stderr.String())
+	}
+	if len(*deleted) != 0 {
+		t.Fatalf("Expected nothing to be deleted, got %v", *deleted)
+	}
+
+	// The blocked run is recorded along with why it was aborted
+	want := "refusing to delete 2 forks, more than max-delete 1"
+	rep, err := readReport(reportPath)
+	if err != nil {
+		t.Fatalf("readReport() failed: %v", err)
+	}
+	if rep.Aborted != want || len(rep.Repos) != 2 || rep.Repos[0].Deleted {
+		t.Errorf("Expected the report of the aborted run, got %+v", rep)
+	}
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("Expected the summary to be written: %v", err)
+	}
+	var sum summary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		t.Fatalf("Invalid summary %q: %v", data, err)
 	}
-}
-
-func TestWithDeleteRepos_Option(t *testing.T) {
-	t.Parallel()
-	config := NewCLIConfig(nil, nil, "").withDeleteRepos(mockDeleteRepos)
-	if config.deleteRepos == nil {
-		t.Fatal("WithDeleteRepos did not set the function")
+	if sum.Aborted != want || sum.Unguarded != 2 || sum.Deleted != 0 {
+		t.Errorf("Expected the summary of the aborted run, got %+v", sum)
 	}
 }
 
-func TestCLI_MissingOwnerToken(t *testing.T) {
+func TestCLI_UsesInjectedFilter(t *testing.T) {
 	t.Parallel()
 
 	stdout := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
+	called := false
 
 	cliConfig := NewCLIConfig(
 		stdout,
 		stderr,
 		"test-version",
-	).withFetchForkedRepos(mockFetchForkedRepos).
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
 		withDeleteRepos(mockDeleteRepos).
 		withFlagErrorHandling(mockFlagErrorHandler).
-		withFilterForkedRepos(mockFilterForkedRepos)
+		withFilterForkedRepos(func(forkedRepos []Repo, opts filterOptions) ([]Repo, []Repo) {
+			called = true
+			return nil, forkedRepos
+		})
 
-		// Execute the CLI
-	exitCode := cliConfig.CLI([]string{"cmd"})
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--delete"}
+	exitCode := cliConfig.CLI(args)
 
-	if !strings.Contains(stderr.String(), "owner and token are required") {
-		t.Errorf("Expected error message not found in output")
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
 	}
-
-	if exitCode != 1 {
-		t.Errorf("Expected os.Exit to be called once, got %d", exitCode)
+	if !called {
+		t.Errorf("Expected the injected filter to be called")
+	}
+	if !strings.Contains(stdout.String(), "No unguarded forked repositories to delete") {
+		t.Errorf("Expected the injected filter's result to be used, got %q", stdout.String())
 	}
 }
-func TestCLI_Success(t *testing.T) {
+
+func TestCLI_Stream(t *testing.T) {
 	t.Parallel()
 
 	stdout := new(bytes.Buffer)
@@ -552,17 +2615,648 @@ func TestCLI_Success(t *testing.T) {
 		stdout,
 		stderr,
 		"test-version",
-	).withDeleteRepos(mockDeleteRepos).
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
 		withFetchForkedRepos(mockFetchForkedRepos).
-		withFlagErrorHandling(mockFlagErrorHandler).
-		withFilterForkedRepos(mockFilterForkedRepos)
-
-	// Execute the CLI
-	args := []string{"--owner", "testOwner", "--token", "testToken", "--older-than-days", "30"}
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
 
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--stream"}
 	exitCode := cliConfig.CLI(args)
 
 	if exitCode != 0 {
 		t.Errorf("Expected exit code 0, got %d", exitCode)
 	}
+	if !strings.Contains(stdout.String(), "Decisions:\n    delete test-repo (last active ") {
+		t.Errorf("Expected streamed decision, got %q", stdout.String())
+	}
+}
+
+// Test the fully wired cli flow against a fake GitHub API
+
+// newMockGitHubServer serves the user, listing and delete endpoints for the given
+// forks owned by testOwner, authenticated as testOwner. The users otherUser and
+// testOrg also exist. It records the full names of the repos that were
+// deleted. Requests not authenticated with testToken are rejected with a 401.
+func newMockGitHubServer(t *testing.T, forks []Repo) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var (
+		mu      sync.Mutex
+		deleted []string
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(
+			w,
+			`{"login": "testOwner", "type": "User", "public_repos": 12, "total_private_repos": 3}`)
+	})
+	mux.HandleFunc("GET /users/{owner}", func(w http.ResponseWriter, r *http.Request) {
+		switch owner := r.PathValue("owner"); owner {
+		case "testOwner", "otherUser":
+			fmt.Fprintf(w, `{"login": %q, "type": "User", "public_repos": 7}`, owner)
+		case "testOrg":
+			fmt.Fprintf(w, `{"login": %q, "type": "Organization"}`, owner)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	listForks := func(w http.ResponseWriter, r *http.Request) {
+		// Serve every fork on the first page and an empty second page
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprintln(w, "[]")
+			return
+		}
+		json.NewEncoder(w).Encode(forks)
+	}
+	mux.HandleFunc("GET /users/{owner}/repos", listForks)
+	mux.HandleFunc("GET /user/repos", listForks)
+	mux.HandleFunc("GET /orgs/{owner}/repos", listForks)
+	mux.HandleFunc("GET /repos/{owner}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, fork := range forks {
+			if fork.Name == r.PathValue("name") {
+				json.NewEncoder(w).Encode(fork)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("DELETE /repos/{owner}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		deleted = append(deleted, r.PathValue("owner")+"/"+r.PathValue("name"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer testToken" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		}))
+	t.Cleanup(server.Close)
+	return server, &deleted
+}
+
+func newMockFork(name string, lastActivity time.Time) Repo {
+	r := Repo{
+		Name:      name,
+		URL:       "https://github.com/testOwner/" + name,
+		IsFork:    true,
+		CreatedAt: lastActivity,
+		UpdatedAt: lastActivity,
+		PushedAt:  lastActivity,
+	}
+	r.Owner.Name = "testOwner"
+	return r
+}
+
+func TestCLI_Integration(t *testing.T) {
+	t.Parallel()
+	forks := []Repo{
+		newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0)),
+		newMockFork("guarded-stale-repo", time.Now().AddDate(-1, 0, 0)),
+		newMockFork("active-repo", time.Now()),
+	}
+	server, deleted := newMockGitHubServer(t, forks)
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner",
+		"--token", "testToken",
+		"--guard", "guarded",
+		"--delete",
+	}
+	exitCode := cliConfig.CLI(args)
+
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	expected := []string{"testOwner/stale-repo"}
+	if !reflect.DeepEqual(*deleted, expected) {
+		t.Errorf("Expected deleted repos %v, got %v", expected, *deleted)
+	}
+	if !strings.Contains(stdout.String(), "Found 3 forks (testOwner has 12 public and 3 private repos)") {
+		t.Errorf("Expected a summary with repo counts, got %q", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "Deleted 1 fork in ") ||
+		!strings.Contains(stdout.String(), "(finished at ") {
+		t.Errorf("Expected success message with count and duration, got %q", stdout.String())
+	}
+}
+
+func TestCLI_HealthCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		owner        string
+		token        string
+		wantExitCode int
+		wantOutput   string
+	}{
+		{"healthy", "testOwner", "testToken", 0, "Health check passed"},
+		{"invalid token", "testOwner", "badToken", exitAuth, "Error: invalid token"},
+		{"unknown owner", "unknownOwner", "testToken", 1, "Error: user not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, deleted := newMockGitHubServer(
+				t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{
+				"--owner", tt.owner,
+				"--token", tt.token,
+				"--health-check",
+				"--delete",
+			}
+			exitCode := cliConfig.CLI(args)
+
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantExitCode, exitCode)
+			}
+			if output := stdout.String() + stderr.String(); !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got %q", tt.wantOutput, output)
+			}
+			if len(*deleted) != 0 {
+				t.Errorf("Expected health check not to delete anything, got %v", *deleted)
+			}
+		})
+	}
+}
+
+func TestCLI_EndpointSelection(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		owner        string
+		ownerType    string
+		authLogin    string
+		authErr      error
+		wantEndpoint string
+	}{
+		{"token owner", "testOwner", ownerTypeAuto, "TestOwner", nil, endpointUser},
+		{"other user", "testOwner", ownerTypeAuto, "someone-else", nil, endpointUsers},
+		{
+			"unresolved user",
+			"testOwner",
+			ownerTypeAuto,
+			"",
+			fmt.Errorf("API request failed with status: 403"),
+			endpointUsers,
+		},
+		{"detected org", "testOrg", ownerTypeAuto, "testOwner", nil, endpointOrgs},
+		{"explicit org", "testOwner", ownerTypeOrg, "testOwner", nil, endpointOrgs},
+		{"explicit user", "testOrg", ownerTypeUser, "testOwner", nil, endpointUsers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			var gotEndpoint string
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withFetchAuthenticatedUser(func(
+				ctx context.Context,
+				baseURL,
+				token string) (user, error) {
+				return user{Login: tt.authLogin}, tt.authErr
+			}).
+				withFetchUser(mockFetchUser).
+				withFetchForkedRepos(func(
+					ctx context.Context,
+					baseURL,
+					owner,
+					token,
+					endpoint string,
+					perPage,
+					maxPage int) ([]Repo, error) {
+					gotEndpoint = endpoint
+					return nil, nil
+				}).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			exitCode := cliConfig.CLI([]string{
+				"--owner", tt.owner, "--token", "testToken", "--owner-type", tt.ownerType,
+			})
+
+			if exitCode != 0 {
+				t.Errorf("Expected exit code 0, got %d", exitCode)
+			}
+			if gotEndpoint != tt.wantEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", tt.wantEndpoint, gotEndpoint)
+			}
+		})
+	}
+}
+
+func TestCLI_OwnerFromToken(t *testing.T) {
+	t.Parallel()
+	server, _ := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+	// Answering /user as a bot for the app token
+	withBot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user" {
+			fmt.Fprintln(w, `{"login": "sweeper[bot]", "type": "Bot"}`)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer withBot.Close()
+
+	tests := []struct {
+		name         string
+		baseURL      string
+		args         []string
+		wantExitCode int
+		wantOutput   string
+	}{
+		{
+			name:         "user token",
+			baseURL:      server.URL,
+			args:         []string{"--owner-from-token"},
+			wantExitCode: 0,
+			wantOutput:   "https://github.com/testOwner/stale-repo",
+		},
+		{
+			name:         "app token",
+			baseURL:      withBot.URL,
+			args:         []string{"--owner-from-token"},
+			wantExitCode: 1,
+			wantOutput:   "Error: token belongs to bot sweeper[bot] rather than a user",
+		},
+		{
+			name:         "with owner",
+			baseURL:      server.URL,
+			args:         []string{"--owner-from-token", "--owner", "testOwner"},
+			wantExitCode: 1,
+			wantOutput:   "mutually exclusive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(tt.baseURL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			exitCode := cliConfig.CLI(append([]string{"--token", "testToken"}, tt.args...))
+
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.wantExitCode, exitCode, stderr.String())
+			}
+			if output := stdout.String() + stderr.String(); !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got %q", tt.wantOutput, output)
+			}
+		})
+	}
+}
+
+func TestCLI_ConfirmTokenOwner(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantOutput   string
+	}{
+		{
+			name:         "token owner",
+			args:         []string{"--owner", "testOwner", "--confirm-token-owner"},
+			wantExitCode: 0,
+			wantOutput:   "Unguarded forked repos",
+		},
+		{
+			name:         "org owner",
+			args:         []string{"--owner", "testOrg", "--confirm-token-owner"},
+			wantExitCode: 0,
+			wantOutput:   "Unguarded forked repos",
+		},
+		{
+			name:         "other user",
+			args:         []string{"--owner", "otherUser", "--confirm-token-owner"},
+			wantExitCode: exitAuth,
+			wantOutput:   "Error: token belongs to testOwner, not otherUser",
+		},
+		{
+			name: "other user allowed",
+			args: []string{
+				"--owner", "otherUser", "--confirm-token-owner", "--allow-cross-owner",
+			},
+			wantExitCode: 0,
+			wantOutput:   "Warning: token belongs to testOwner, not otherUser",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newMockGitHubServer(
+				t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			exitCode := cliConfig.CLI(append([]string{"--token", "testToken"}, tt.args...))
+
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantExitCode, exitCode)
+			}
+			if output := stdout.String() + stderr.String(); !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got %q", tt.wantOutput, output)
+			}
+		})
+	}
+}
+
+func TestCLI_NoForksHintsAtRepoScope(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		scopes     string
+		wantOutput string
+	}{
+		{"without repo scope", "public_repo", "private forks require the 'repo' scope"},
+		{"with repo scope", "repo, user", "No forked repositories found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newMockGitHubServer(t, nil)
+			scoped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-OAuth-Scopes", tt.scopes)
+				server.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer scoped.Close()
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{
+				"--owner", "testOwner",
+				"--token", "testToken",
+				"--api-url", scoped.URL,
+			}
+			if exitCode := cliConfig.CLI(args); exitCode != 0 {
+				t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got %q", tt.wantOutput, stdout.String())
+			}
+		})
+	}
+}
+
+func TestCLI_APIURL(t *testing.T) {
+	t.Parallel()
+	var gotQuery string
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+	// Prefix the mock API with /api/v1 like Gitea and record the listing query
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/repos") {
+			gotQuery = r.URL.RawQuery
+		}
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/api/v1")
+		server.Config.Handler.ServeHTTP(w, r)
+	})
+	gitea := httptest.NewServer(mux)
+	defer gitea.Close()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner",
+		"--token", "testToken",
+		"--owner-type", "user",
+		"--api-url", gitea.URL + "/api/v1/",
+		"--per-page-param", "limit",
+		"--no-type-param",
+		"--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if !strings.Contains(stdout.String(), "https://github.com/testOwner/stale-repo") {
+		t.Errorf("Expected the fork to be listed, got %q", stdout.String())
+	}
+	if gotQuery != "affiliation=owner&page=2&limit=100&sort=pushed&direction=desc" {
+		t.Errorf("Unexpected listing query %q", gotQuery)
+	}
+
+	// Deletes go through the same prefix as the listing
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/stale-repo" {
+		t.Errorf("Expected stale-repo to be deleted through the prefix, got %v", *deleted)
+	}
+}
+
+func TestFilterForkedRepos_ProtectIssues(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "tracked-repo", OpenIssues: 3, CreatedAt: old, UpdatedAt: old, PushedAt: old},
+		{Name: "quiet-repo", CreatedAt: old, UpdatedAt: old, PushedAt: old},
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		maxAhead:      -1,
+		protectIssues: true,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "quiet-repo" {
+		t.Errorf("Expected only quiet-repo to be unguarded, got %v", unguarded)
+	}
+	if reasons["tracked-repo"] != "3 open issues" {
+		t.Errorf("Expected reason '3 open issues', got %q", reasons["tracked-repo"])
+	}
+}
+
+func TestCLI_BaseURL(t *testing.T) {
+	t.Parallel()
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--base-url", server.URL, "--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	// Both the listing and the deletes go to the mock
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/stale-repo" {
+		t.Errorf("Expected stale-repo to be deleted through the mock, got %v", *deleted)
+	}
+}
+
+func TestFilterForkedRepos_Stars(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "unstarred-repo", CreatedAt: old, UpdatedAt: old, PushedAt: old},
+		{Name: "starred-repo", Stars: 1, CreatedAt: old, UpdatedAt: old, PushedAt: old},
+		{Name: "popular-repo", Stars: 42, CreatedAt: old, UpdatedAt: old, PushedAt: old},
+	}
+
+	tests := []struct {
+		name          string
+		minStars      int
+		maxStars      int
+		wantUnguarded []string
+		wantReasons   map[string]string
+	}{
+		{"disabled", -1, -1, []string{"unstarred-repo", "starred-repo", "popular-repo"}, nil},
+		{"max", -1, 5, []string{"unstarred-repo", "starred-repo"}, map[string]string{"popular-repo": "42 stars"}},
+		{"min", 1, -1, []string{"starred-repo", "popular-repo"}, map[string]string{"unstarred-repo": "0 stars"}},
+		{"range", 1, 1, []string{"starred-repo"}, map[string]string{"popular-repo": "42 stars"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := map[string]string{}
+			unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+				olderThanDays: 30,
+				maxAhead:      -1,
+				minStars:      tt.minStars,
+				maxStars:      tt.maxStars,
+				onDecision: func(r Repo, guarded bool, reason string) {
+					reasons[r.Name] = reason
+				},
+			})
+
+			var names []string
+			for _, r := range unguarded {
+				names = append(names, r.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantUnguarded) {
+				t.Errorf("Expected %v to be unguarded, got %v", tt.wantUnguarded, names)
+			}
+			for name, want := range tt.wantReasons {
+				if reasons[name] != want {
+					t.Errorf("Expected %s to be kept for %q, got %q", name, want, reasons[name])
+				}
+			}
+		})
+	}
+}
+
+func TestCLI_Detach(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", old), newMockFork("active-repo", time.Now())})
+
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		new(bytes.Buffer),
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--detach"}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d", exitErr, exitCode)
+	}
+	for _, want := range []string{
+		"Error: detach is not supported on this host",
+		"    - https://github.com/testOwner/stale-repo/settings\n",
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in the output, got %q", want, stderr.String())
+		}
+	}
+	if strings.Contains(stderr.String(), "active-repo") {
+		t.Errorf("Expected only the unguarded fork to be listed, got %q", stderr.String())
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("Expected nothing to be deleted, got %v", *deleted)
+	}
+}
+
+func TestCLI_DetachWithDelete(t *testing.T) {
+	t.Parallel()
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+		withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--detach", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d", exitErr, exitCode)
+	}
+	if !strings.Contains(stderr.String(), "detach can't be combined with delete") {
+		t.Errorf("Expected error message not found in output: %q", stderr)
+	}
 }
@@ -0,0 +1,125 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// comparison is the part of the compare API's response that we use
+type comparison struct {
+	AheadBy  int `json:"ahead_by"`
+	BehindBy int `json:"behind_by"`
+
+	// The commits the fork is ahead by. Authors whose email isn't linked to an
+	// account come back null.
+	Commits []struct {
+		Author *struct {
+			Login string `json:"login"`
+		} `json:"author"`
+	} `json:"commits"`
+}
+
+// committedByOthers reports whether anyone but owner authored a commit the fork
+// is ahead by. Unlinked authors can't be told apart, so they count as others.
+func (c comparison) committedByOthers(owner string) bool {
+	for _, commit := range c.Commits {
+		if commit.Author == nil || !strings.EqualFold(commit.Author.Login, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchComparison compares the fork's default branch against its parent's
+func fetchComparison(ctx context.Context, baseURL, token string, r Repo) (comparison, error) {
+	var c comparison
+
+	url := fmt.Sprintf(
+		"%s/repos/%s/compare/%s...%s:%s",
+		baseURL,
+		r.Parent.FullName,
+		r.Parent.DefaultBranch,
+		r.Owner.Name,
+		r.DefaultBranch)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return c, err
+	}
+
+	err = doRequest(req, token, &c)
+	return c, err
+}
+
+// fetchAheadCounts fills in how many commits every fork is ahead of its parent.
+// Forks without a parent, or whose branches can't be compared, e.g. because the
+// fork is empty, are left without a count.
+func fetchAheadCounts(ctx context.Context, baseURL, token string, repos []Repo) error {
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	sem := newReadSlots(ctx)
+
+	for i := range repos {
+		if repos[i].Parent == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *Repo) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+
+			c, err := fetchComparison(ctx, baseURL, token, *r)
+			if err != nil && err.Error() == ErrMsg404 {
+				return
+			}
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("comparing %s: %w", repoKey(*r), err):
+				default:
+				}
+				return
+			}
+			r.aheadBy = c.AheadBy
+			r.othersCommitted = c.committedByOthers(r.Owner.Name)
+			r.compared = true
+		}(&repos[i])
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	if len(errChan) > 0 {
+		return <-errChan
+	}
+	return nil
+}
+
+// compareURL is the web page comparing the fork's default branch against its
+// parent's, or empty when the parent or either branch is unknown
+func compareURL(r Repo) string {
+	if r.Parent == nil || r.Parent.URL == "" || r.Parent.DefaultBranch == "" || r.DefaultBranch == "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s/compare/%s...%s:%s",
+		r.Parent.URL,
+		r.Parent.DefaultBranch,
+		r.Owner.Name,
+		r.DefaultBranch)
+}
+
+// formatAhead describes how far a fork is ahead of its parent
+func formatAhead(r Repo) string {
+	switch {
+	case !r.compared:
+		return "ahead count unknown"
+	case r.aheadBy == 1:
+		return "1 commit ahead"
+	default:
+		return fmt.Sprintf("%d commits ahead", r.aheadBy)
+	}
+}
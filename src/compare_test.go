@@ -0,0 +1,384 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestParent(name string) *repoParent {
+	return &repoParent{FullName: "upstream/" + name, DefaultBranch: "main"}
+}
+
+func TestFetchAheadCounts(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/upstream/diverged-repo/compare/main...test-owner:dev":
+				fmt.Fprintln(w, `{"ahead_by": 12, "behind_by": 3}`)
+			case "/repos/upstream/synced-repo/compare/main...test-owner:main":
+				fmt.Fprintln(w, `{"ahead_by": 0, "behind_by": 0}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	repos := []Repo{
+		newTestRepo("diverged-repo"),
+		newTestRepo("synced-repo"),
+		newTestRepo("empty-repo"),
+		newTestRepo("orphaned-repo"),
+	}
+	repos[0].Parent, repos[0].DefaultBranch = newTestParent("diverged-repo"), "dev"
+	repos[1].Parent, repos[1].DefaultBranch = newTestParent("synced-repo"), "main"
+	repos[2].Parent, repos[2].DefaultBranch = newTestParent("empty-repo"), "main"
+
+	if err := fetchAheadCounts(context.Background(), server.URL, "test-token", repos); err != nil {
+		t.Fatalf("fetchAheadCounts() failed: %v", err)
+	}
+
+	expected := []string{
+		"12 commits ahead",
+		"0 commits ahead",
+		"ahead count unknown",
+		"ahead count unknown",
+	}
+	for i, r := range repos {
+		if got := formatAhead(r); got != expected[i] {
+			t.Errorf("Expected %s to be %q, got %q", r.Name, expected[i], got)
+		}
+	}
+}
+
+func TestFetchAheadCounts_Error(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("forbidden-repo")}
+	repos[0].Parent = newTestParent("forbidden-repo")
+
+	err := fetchAheadCounts(context.Background(), server.URL, "test-token", repos)
+	if err == nil || !strings.Contains(err.Error(), "test-owner/forbidden-repo") {
+		t.Errorf("Expected an error naming the repo, got %v", err)
+	}
+}
+
+func TestFilterForkedRepos_MaxAhead(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "trivial-repo", compared: true, aheadBy: 2},
+		{Name: "diverged-repo", compared: true, aheadBy: 3},
+		{Name: "unknown-repo", aheadBy: 0},
+	}
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, guarded := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		maxAhead:      2,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(guarded) != 1 || guarded[0].Name != "diverged-repo" {
+		t.Errorf("Expected only diverged-repo to be guarded, got %v", guarded)
+	}
+	if len(unguarded) != 2 {
+		t.Errorf("Expected trivial-repo and unknown-repo to be unguarded, got %v", unguarded)
+	}
+	if reasons["diverged-repo"] != "3 commits ahead" {
+		t.Errorf("Expected reason '3 commits ahead', got %q", reasons["diverged-repo"])
+	}
+}
+
+func TestCLI_MaxAhead(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	trivial := newMockFork("trivial-repo", old)
+	diverged := newMockFork("diverged-repo", old)
+	for _, r := range []*Repo{&trivial, &diverged} {
+		r.DefaultBranch = "main"
+		r.Parent = newTestParent(r.Name)
+	}
+	server, deleted := newMockGitHubServer(t, []Repo{trivial, diverged})
+
+	// Serve the compare API next to the mock GitHub API
+	compare := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/upstream/trivial-repo/compare/main...testOwner:main":
+				fmt.Fprintln(w, `{"ahead_by": 1}`)
+			case "/repos/upstream/diverged-repo/compare/main...testOwner:main":
+				fmt.Fprintln(w, `{"ahead_by": 40}`)
+			default:
+				server.Config.Handler.ServeHTTP(w, r)
+			}
+		}))
+	defer compare.Close()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(compare.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--max-ahead", "2", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/trivial-repo" {
+		t.Errorf("Expected only trivial-repo to be deleted, got %v", *deleted)
+	}
+	for _, want := range []string{
+		"https://github.com/testOwner/diverged-repo (40 commits ahead)",
+		"https://github.com/testOwner/trivial-repo (1 commit ahead)",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %q in output, got %q", want, stdout.String())
+		}
+	}
+}
+
+func TestFetchAheadCounts_Concurrency(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprintln(w, `{"ahead_by": 0, "behind_by": 0}`)
+		}))
+	defer server.Close()
+
+	var repos []Repo
+	for i := range 6 {
+		r := newTestRepo(fmt.Sprintf("repo-%d", i))
+		r.Parent, r.DefaultBranch = newTestParent(r.Name), "main"
+		repos = append(repos, r)
+	}
+
+	ctx := withRequestConfig(context.Background(), requestConfig{readConcurrency: 2})
+	if err := fetchAheadCounts(ctx, server.URL, "test-token", repos); err != nil {
+		t.Fatalf("fetchAheadCounts() failed: %v", err)
+	}
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("Expected at most 2 concurrent comparisons, got %d", got)
+	}
+}
+
+func TestFilterForkedRepos_EmptyOnly(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "empty-repo", Size: 0},
+		{Name: "synced-repo", Size: 120, compared: true, aheadBy: 0},
+		{Name: "diverged-repo", Size: 120, compared: true, aheadBy: 4},
+		{Name: "unknown-repo", Size: 120},
+	}
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		emptyOnly:     true,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	var got []string
+	for _, r := range unguarded {
+		got = append(got, r.Name)
+	}
+	if expected := []string{"empty-repo", "synced-repo"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected unguarded %v, got %v", expected, got)
+	}
+	if reasons["diverged-repo"] != "4 commits ahead" ||
+		reasons["unknown-repo"] != "not known to be empty" {
+		t.Errorf("Unexpected reasons %v", reasons)
+	}
+}
+
+func TestCLI_DeleteEmptyOnly(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	synced := newMockFork("synced-repo", old)
+	diverged := newMockFork("diverged-repo", old)
+	for _, r := range []*Repo{&synced, &diverged} {
+		r.Size = 120
+		r.DefaultBranch = "main"
+		r.Parent = newTestParent(r.Name)
+	}
+	server, deleted := newMockGitHubServer(t, []Repo{synced, diverged})
+
+	compare := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/upstream/synced-repo/compare/main...testOwner:main":
+				fmt.Fprintln(w, `{"ahead_by": 0}`)
+			case "/repos/upstream/diverged-repo/compare/main...testOwner:main":
+				fmt.Fprintln(w, `{"ahead_by": 2}`)
+			default:
+				server.Config.Handler.ServeHTTP(w, r)
+			}
+		}))
+	defer compare.Close()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(compare.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--delete-empty-only", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if !reflect.DeepEqual(*deleted, []string{"testOwner/synced-repo"}) {
+		t.Errorf("Expected only synced-repo to be deleted, got %v: %s", *deleted, stdout)
+	}
+}
+
+func TestCompareURL(t *testing.T) {
+	t.Parallel()
+	repo := newTestRepo("test-repo")
+	if got := compareURL(repo); got != "" {
+		t.Errorf("Expected no compare URL without a parent, got %q", got)
+	}
+
+	repo.Parent, repo.DefaultBranch = newTestParent("test-repo"), "dev"
+	repo.Parent.URL = "https://github.com/upstream/test-repo"
+	expected := "https://github.com/upstream/test-repo/compare/main...test-owner:dev"
+	if got := compareURL(repo); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestCLI_DiffUpstreamURL(t *testing.T) {
+	t.Parallel()
+	fork := newMockFork("test-repo", time.Now().AddDate(-1, 0, 0))
+	fork.DefaultBranch = "main"
+	fork.Parent = &repoParent{
+		FullName:      "upstream/test-repo",
+		URL:           "https://github.com/upstream/test-repo",
+		DefaultBranch: "master",
+	}
+	server, _ := newMockGitHubServer(t, []Repo{fork})
+
+	expected := "https://github.com/upstream/test-repo/compare/master...testOwner:main"
+	for _, format := range []string{outputFormatText, outputFormatJSON} {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		cliConfig := NewCLIConfig(stdout, stderr, "test-version").
+			withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args := []string{
+			"--owner", "testOwner", "--token", "testToken", "--diff-upstream-url",
+			"--output-format", format,
+		}
+		if exitCode := cliConfig.CLI(args); exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("Expected the %s output to link %s, got %q", format, expected, stdout)
+		}
+	}
+}
+
+func TestFetchAheadCounts_OthersCommitted(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/upstream/own-repo/compare/main...test-owner:main":
+				fmt.Fprintln(w, `{"ahead_by": 1, "commits": [{"author": {"login": "Test-Owner"}}]}`)
+			case "/repos/upstream/shared-repo/compare/main...test-owner:main":
+				fmt.Fprintln(w, `{"ahead_by": 2, "commits": [`+
+					`{"author": {"login": "test-owner"}}, {"author": {"login": "collaborator"}}]}`)
+			case "/repos/upstream/unlinked-repo/compare/main...test-owner:main":
+				fmt.Fprintln(w, `{"ahead_by": 1, "commits": [{"author": null}]}`)
+			}
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("own-repo"), newTestRepo("shared-repo"), newTestRepo("unlinked-repo")}
+	for i := range repos {
+		repos[i].Parent, repos[i].DefaultBranch = newTestParent(repos[i].Name), "main"
+	}
+
+	if err := fetchAheadCounts(context.Background(), server.URL, "test-token", repos); err != nil {
+		t.Fatalf("fetchAheadCounts() failed: %v", err)
+	}
+
+	expected := []bool{false, true, true}
+	for i, r := range repos {
+		if r.othersCommitted != expected[i] {
+			t.Errorf("Expected %s to have commits by others %v, got %v", r.Name, expected[i], r.othersCommitted)
+		}
+	}
+}
+
+func TestFilterForkedRepos_ProtectOthers(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "own-repo", compared: true, aheadBy: 1},
+		{Name: "shared-repo", compared: true, aheadBy: 2, othersCommitted: true},
+	}
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		maxAhead:      -1,
+		protectOthers: true,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "own-repo" {
+		t.Errorf("Expected only own-repo to be unguarded, got %v", unguarded)
+	}
+	if reasons["shared-repo"] != "has commits by others" {
+		t.Errorf("Expected reason 'has commits by others', got %q", reasons["shared-repo"])
+	}
+}
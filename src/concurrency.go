@@ -0,0 +1,114 @@
+package src
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Concurrency the auto mode starts at before ramping up
+const initialAutoConcurrency = 2
+
+// Remaining requests below which the auto mode backs off as if rate limited
+const minRateLimitHeadroom = 100
+
+// readSlots bounds the concurrent per-fork reads of a run
+type readSlots interface {
+	acquire()
+	release()
+}
+
+// fixedSlots allows a fixed number of concurrent reads
+type fixedSlots chan struct{}
+
+func (s fixedSlots) acquire() { s <- struct{}{} }
+func (s fixedSlots) release() { <-s }
+
+// newReadSlots returns the run's auto concurrency controller when set, or a
+// fixed number of slots otherwise
+func newReadSlots(ctx context.Context) readSlots {
+	cfg := requestConfigFrom(ctx)
+	if cfg.autoConcurrency != nil {
+		return cfg.autoConcurrency
+	}
+	return make(fixedSlots, cfg.readConcurrency)
+}
+
+// concurrencyController scales concurrent reads to the rate limit headroom, AIMD
+// style: the limit grows by one after a limit's worth of responses served, 404s
+// included, and halves on a rate limited response or when few requests remain.
+// It's shared by every request of a run, so it learns from all of them. A nil
+// controller ignores responses.
+type concurrencyController struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	max      int
+	inFlight int
+
+	successes     int // since the limit last grew
+	sinceDecrease int // responses since the limit last shrank
+	cooldown      int // responses to ignore after shrinking, the requests then in flight
+}
+
+func newConcurrencyController(initial, ceiling int) *concurrencyController {
+	c := &concurrencyController{limit: min(initial, ceiling), max: ceiling}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// acquire blocks until a read fits under the current limit
+func (c *concurrencyController) acquire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.inFlight >= c.limit {
+		c.cond.Wait()
+	}
+	c.inFlight++
+}
+
+func (c *concurrencyController) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	c.cond.Broadcast()
+}
+
+// observe adjusts the limit to a response. Responses to requests already in
+// flight when the limit halved don't halve it again.
+func (c *concurrencyController) observe(resp *http.Response) {
+	if c == nil || resp == nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	lowHeadroom := err == nil && remaining < minRateLimitHeadroom
+	limited := isRateLimited(resp)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sinceDecrease++
+	switch {
+	case limited || lowHeadroom:
+		if c.sinceDecrease > c.cooldown {
+			c.cooldown = c.inFlight
+			c.limit = max(c.limit/2, 1)
+			c.successes, c.sinceDecrease = 0, 0
+		}
+	case resp.StatusCode < http.StatusInternalServerError:
+		c.successes++
+		if c.successes >= c.limit && c.limit < c.max {
+			c.limit++
+			c.successes = 0
+			c.cond.Broadcast()
+		}
+	}
+}
+
+// current returns the limit the controller settled at
+func (c *concurrencyController) current() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
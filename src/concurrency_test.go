@@ -0,0 +1,112 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func limitedResponse(status, remaining int) *http.Response {
+	resp := rateLimitResponse("", remaining, 0)
+	resp.StatusCode = status
+	return resp
+}
+
+func TestConcurrencyController_AIMD(t *testing.T) {
+	t.Parallel()
+	c := newConcurrencyController(2, 4)
+	observe := func(n int, resp *http.Response) {
+		for i := 0; i < n; i++ {
+			c.observe(resp)
+		}
+	}
+
+	// Growing by one after a limit's worth of successes, up to the ceiling
+	observe(2, limitedResponse(http.StatusOK, 5000))
+	if got := c.current(); got != 3 {
+		t.Fatalf("Expected the limit to grow to 3, got %d", got)
+	}
+	observe(10, limitedResponse(http.StatusOK, 5000))
+	if got := c.current(); got != 4 {
+		t.Fatalf("Expected the limit to stop at the ceiling of 4, got %d", got)
+	}
+
+	// Halving once for the responses of the requests in flight when rate limited
+	for i := 0; i < 4; i++ {
+		c.acquire()
+	}
+	observe(5, limitedResponse(http.StatusTooManyRequests, 0))
+	if got := c.current(); got != 2 {
+		t.Fatalf("Expected the limit to halve once to 2, got %d", got)
+	}
+	c.observe(limitedResponse(http.StatusTooManyRequests, 0))
+	if got := c.current(); got != 1 {
+		t.Fatalf("Expected the limit to halve again to 1, got %d", got)
+	}
+	observe(10, limitedResponse(http.StatusForbidden, 0))
+	if got := c.current(); got != 1 {
+		t.Errorf("Expected the limit to never drop below 1, got %d", got)
+	}
+}
+
+func TestConcurrencyController_LowHeadroom(t *testing.T) {
+	t.Parallel()
+	c := newConcurrencyController(4, 4)
+	c.observe(limitedResponse(http.StatusOK, minRateLimitHeadroom-1))
+	if got := c.current(); got != 2 {
+		t.Errorf("Expected low headroom to halve the limit to 2, got %d", got)
+	}
+
+	var nilController *concurrencyController
+	nilController.observe(limitedResponse(http.StatusOK, 0))
+}
+
+func TestFetchMarkers_AutoConcurrency(t *testing.T) {
+	t.Parallel()
+	// Serving a rate limit that decays by one with every request
+	var (
+		mu        sync.Mutex
+		remaining = 150
+		inFlight  int
+		peak      int
+	)
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			remaining--
+			inFlight++
+			peak = max(peak, inFlight)
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			mu.Unlock()
+
+			time.Sleep(2 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	defer server.Close()
+
+	repos := make([]Repo, 100)
+	for i := range repos {
+		repos[i] = newTestRepo(fmt.Sprintf("repo-%d", i))
+	}
+	c := newConcurrencyController(initialAutoConcurrency, 8)
+	ctx := withRequestConfig(context.Background(), requestConfig{autoConcurrency: c})
+
+	if err := fetchMarkers(ctx, server.URL, "test-token", ".keep", repos); err != nil {
+		t.Fatalf("fetchMarkers() failed: %v", err)
+	}
+	if peak <= initialAutoConcurrency || peak > 8 {
+		t.Errorf("Expected concurrency to ramp up to at most 8, peaked at %d", peak)
+	}
+	if got := c.current(); got != 1 {
+		t.Errorf("Expected concurrency to back off to 1 as the rate limit ran low, got %d", got)
+	}
+}
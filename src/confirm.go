@@ -0,0 +1,78 @@
+package src
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// confirmEach prompts for every repo and returns the ones approved for the
+// action, e.g. "Delete". Answering 'a' approves the repo and all the remaining ones, 'q' stops and
+// skips them. Anything but 'y' skips the repo, as does running out of input.
+// The input is read in the background, so that cancelling ctx, e.g. on Ctrl-C,
+// stops the prompt with ctx's error instead of waiting for an answer.
+func confirmEach(
+	ctx context.Context,
+	in io.Reader,
+	out io.Writer,
+	action string,
+	repos []Repo,
+	now time.Time) ([]Repo, error) {
+
+	lines := readLines(ctx, in)
+	confirmed := []Repo{}
+
+	for i, r := range repos {
+		fmt.Fprintf(
+			out,
+			"%s %s (pushed %s)? [y/N/a/q] ",
+			action,
+			repoKey(r),
+			formatAge(now, r.PushedAt))
+
+		var line string
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(out)
+			return confirmed, ctx.Err()
+		case l, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(out)
+				return confirmed, nil
+			}
+			line = l
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			confirmed = append(confirmed, r)
+		case "a", "all":
+			return append(confirmed, repos[i:]...), nil
+		case "q", "quit":
+			return confirmed, nil
+		}
+	}
+	return confirmed, nil
+}
+
+// readLines sends the lines of in until it runs out of input or ctx is done. A
+// read blocked on a terminal can't be interrupted, so the goroutine is left
+// behind in that case; the process is about to exit anyway.
+func readLines(ctx context.Context, in io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return lines
+}
@@ -0,0 +1,125 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfirmEach(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	repos := []Repo{newTestRepo("repo-1"), newTestRepo("repo-2"), newTestRepo("repo-3")}
+	for i := range repos {
+		repos[i].PushedAt = now.AddDate(0, 0, -5)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"each", "y\nn\nY\n", []string{"repo-1", "repo-3"}},
+		{"default no", "\n\n\n", nil},
+		{"all", "n\na\n", []string{"repo-2", "repo-3"}},
+		{"quit", "y\nq\n", []string{"repo-1"}},
+		{"out of input", "y\n", []string{"repo-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			confirmed, err := confirmEach(
+				context.Background(), strings.NewReader(tt.input), out, "Delete", repos, now)
+			if err != nil {
+				t.Fatalf("confirmEach() failed: %v", err)
+			}
+
+			var got []string
+			for _, r := range confirmed {
+				got = append(got, r.Name)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if !strings.Contains(out.String(), "Delete test-owner/repo-1 (pushed 5d ago)? [y/N/a/q] ") {
+				t.Errorf("Unexpected prompt %q", out.String())
+			}
+		})
+	}
+}
+
+func TestConfirmEach_Cancelled(t *testing.T) {
+	t.Parallel()
+	repos := []Repo{newTestRepo("repo-1"), newTestRepo("repo-2")}
+
+	// The prompt waits on input that never comes, as on an idle terminal
+	in, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := confirmEach(ctx, in, io.Discard, "Delete", repos, time.Now())
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the prompt to return once cancelled")
+	}
+}
+
+func TestCLI_ConfirmEach(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("repo-1", old),
+		newMockFork("repo-2", old),
+	})
+	reportPath := t.TempDir() + "/report.json"
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withStdin(strings.NewReader("n\ny\n")).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner",
+		"--token", "testToken",
+		"--delete",
+		"--confirm-each",
+		"--report", reportPath,
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if !reflect.DeepEqual(*deleted, []string{"testOwner/repo-2"}) {
+		t.Errorf("Expected only repo-2 to be deleted, got %v", *deleted)
+	}
+
+	rep, err := readReport(reportPath)
+	if err != nil {
+		t.Fatalf("readReport() failed: %v", err)
+	}
+	if len(rep.Repos) != 2 || rep.Repos[0].Deleted || !rep.Repos[1].Deleted {
+		t.Errorf("Expected the report to keep the skipped fork undeleted, got %+v", rep.Repos)
+	}
+}
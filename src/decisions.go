@@ -0,0 +1,164 @@
+package src
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decisionEntry is a repo of a triaged export, e.g. a spreadsheet made from
+// --output-format csv with its decision column edited. A repo is marked for
+// deletion by a "delete" decision or a truthy delete column.
+type decisionEntry struct {
+	Owner    string    `json:"owner"`
+	Name     string    `json:"name"`
+	Decision string    `json:"decision"`
+	Delete   string    `json:"-"`
+	PushedAt time.Time `json:"pushed_at"`
+}
+
+func (e decisionEntry) marked() bool {
+	if strings.EqualFold(strings.TrimSpace(e.Decision), decisionDelete) {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(e.Delete)) {
+	case "yes", "y", "x":
+		return true
+	}
+	ok, _ := strconv.ParseBool(strings.TrimSpace(e.Delete))
+	return ok
+}
+
+// readDecisions reads a triaged CSV or JSON export of owner's forks into a plan
+// of the ones marked for deletion. CSV files are detected by their extension,
+// anything else is read as a JSON report or an array of its entries. Entries
+// that don't name an owner belong to owner, and any other owner is an error.
+func readDecisions(path, owner string) (plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan{}, err
+	}
+
+	var entries []decisionEntry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = parseDecisionsCSV(data)
+	} else {
+		entries, err = parseDecisionsJSON(data)
+	}
+	if err != nil {
+		return plan{}, fmt.Errorf("invalid decisions %s: %w", path, err)
+	}
+
+	p := plan{Owner: owner, GeneratedAt: time.Now(), Repos: []planEntry{}}
+	for _, e := range entries {
+		if !e.marked() {
+			continue
+		}
+		if e.Owner == "" {
+			e.Owner = owner
+		}
+		if !strings.EqualFold(e.Owner, owner) {
+			return plan{}, fmt.Errorf(
+				"invalid decisions %s: %s/%s isn't owned by %s", path, e.Owner, e.Name, owner)
+		}
+		p.Repos = append(p.Repos, planEntry{Owner: e.Owner, Name: e.Name, pushedAt: e.PushedAt})
+	}
+	return p, nil
+}
+
+// parseDecisionsCSV reads the columns it needs by their header name, so columns
+// can be reordered or added in the spreadsheet
+func parseDecisionsCSV(data []byte) ([]decisionEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("missing name column")
+	}
+	_, hasDecision := columns["decision"]
+	_, hasDelete := columns["delete"]
+	if !hasDecision && !hasDelete {
+		return nil, fmt.Errorf("missing decision or delete column")
+	}
+
+	field := func(row []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var entries []decisionEntry
+	for n, row := range rows[1:] {
+		e := decisionEntry{
+			Owner:    field(row, "owner"),
+			Name:     field(row, "name"),
+			Decision: field(row, "decision"),
+			Delete:   field(row, "delete"),
+		}
+		if e.Name == "" {
+			continue
+		}
+		if pushedAt := field(row, "pushed_at"); pushedAt != "" {
+			t, err := time.Parse(time.RFC3339, pushedAt)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid pushed_at %q", n+2, pushedAt)
+			}
+			e.PushedAt = t
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// parseDecisionsJSON reads a report written by --report or --output-format json,
+// or a bare array of its entries. A delete field marks entries too, whether a
+// boolean or a string like the CSV column, e.g. "yes" from a spreadsheet export.
+func parseDecisionsJSON(data []byte) ([]decisionEntry, error) {
+	type jsonEntry struct {
+		decisionEntry
+		Delete any `json:"delete"`
+	}
+
+	var items []jsonEntry
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+	} else {
+		var rep struct {
+			Repos []jsonEntry `json:"repos"`
+		}
+		if err := json.Unmarshal(data, &rep); err != nil {
+			return nil, err
+		}
+		items = rep.Repos
+	}
+
+	entries := make([]decisionEntry, len(items))
+	for i, item := range items {
+		entries[i] = item.decisionEntry
+		if item.Delete != nil {
+			entries[i].Delete = fmt.Sprint(item.Delete)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,171 @@
+package src
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeDecisions(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadDecisions(t *testing.T) {
+	t.Parallel()
+	pushedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected []planEntry
+	}{
+		{
+			"exported csv",
+			"forks.csv",
+			"name,url,owner,pushed_at,decision\n" +
+				"repo-1,,test-owner,2024-01-01T00:00:00Z,delete\n" +
+				"repo-2,,test-owner,2024-01-01T00:00:00Z,keep\n",
+			[]planEntry{{Owner: "test-owner", Name: "repo-1", pushedAt: pushedAt}},
+		},
+		{
+			"delete column",
+			"forks.CSV",
+			"Delete,Name\nyes,repo-1\nno,repo-2\nTRUE,repo-3\n,repo-4\n",
+			[]planEntry{{Owner: "test-owner", Name: "repo-1"}, {Owner: "test-owner", Name: "repo-3"}},
+		},
+		{
+			"report",
+			"report.json",
+			`{"owner": "test-owner", "repos": [` +
+				`{"owner": "test-owner", "name": "repo-1", "decision": "delete", "pushed_at": "2024-01-01T00:00:00Z"},` +
+				`{"owner": "test-owner", "name": "repo-2", "decision": "keep"}]}`,
+			[]planEntry{{Owner: "test-owner", Name: "repo-1", pushedAt: pushedAt}},
+		},
+		{
+			"array",
+			"forks.json",
+			`[{"name": "repo-1", "delete": true}, {"name": "repo-2", "delete": false}]`,
+			[]planEntry{{Owner: "test-owner", Name: "repo-1"}},
+		},
+		{
+			"string delete",
+			"forks.json",
+			`[{"name": "repo-1", "delete": "yes"}, {"name": "repo-2", "delete": "no"},` +
+				`{"name": "repo-3", "delete": "TRUE"}, {"name": "repo-4", "delete": ""}]`,
+			[]planEntry{{Owner: "test-owner", Name: "repo-1"}, {Owner: "test-owner", Name: "repo-3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := readDecisions(writeDecisions(t, tt.file, tt.content), "test-owner")
+			if err != nil {
+				t.Fatalf("readDecisions() failed: %v", err)
+			}
+			if !reflect.DeepEqual(p.Repos, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, p.Repos)
+			}
+		})
+	}
+}
+
+func TestReadDecisions_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no decision column", "name,owner\nrepo-1,test-owner\n", "missing decision or delete column"},
+		{"no name column", "owner,decision\ntest-owner,delete\n", "missing name column"},
+		{"other owner", "name,owner,decision\nrepo-1,someone-else,delete\n", "isn't owned by test-owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readDecisions(writeDecisions(t, "forks.csv", tt.content), "test-owner")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCLI_ApplyDecisions(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0).Truncate(time.Second) // as exported
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("marked-repo", old),
+		newMockFork("changed-repo", time.Now()),
+		newMockFork("kept-repo", old),
+	})
+	path := writeDecisions(t, "forks.csv", "name,pushed_at,decision\n"+
+		"marked-repo,"+old.Format(time.RFC3339)+",delete\n"+
+		"changed-repo,"+old.Format(time.RFC3339)+",delete\n"+
+		"kept-repo,,keep\n")
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--apply-decisions", path}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/marked-repo" {
+		t.Errorf("Expected only marked-repo to be deleted, got %v", *deleted)
+	}
+	if !strings.Contains(stderr.String(), "testOwner/changed-repo was pushed to since the export") {
+		t.Errorf("Expected a warning about changed-repo, got %q", stderr.String())
+	}
+}
+
+func TestCLI_ApplyDecisionsMaxDelete(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("repo-1", old),
+		newMockFork("repo-2", old),
+	})
+	path := writeDecisions(t, "forks.csv", "name,decision\nrepo-1,delete\nrepo-2,delete\n")
+
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		new(bytes.Buffer),
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner",
+		"--token", "testToken",
+		"--apply-decisions", path,
+		"--max-delete", "1",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d: %s", exitErr, exitCode, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "refusing to delete 2 forks, more than max-delete 1") {
+		t.Errorf("Expected the decisions to be refused, got %q", stderr.String())
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("Expected nothing to be deleted, got %v", *deleted)
+	}
+}
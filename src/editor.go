@@ -0,0 +1,106 @@
+package src
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runEditor opens path in the user's $VISUAL or $EDITOR, falling back to vi, and
+// waits for it to exit. The variable may carry arguments, e.g. "code --wait".
+func runEditor(path string) error {
+	editor := "vi"
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if v, ok := os.LookupEnv(key); ok && strings.TrimSpace(v) != "" {
+			editor = v
+			break
+		}
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// editList writes the repos to a temporary file, one owner/name per line, has
+// edit open it, and returns the repos whose lines are left once it returns, in
+// their original order. The verb, e.g. "delete", names the action in the
+// file's instructions. Lines naming repos that weren't listed are an error.
+func editList(edit func(path string) error, verb string, repos []Repo, now time.Time) ([]Repo, error) {
+	f, err := os.CreateTemp("", "fork-sweeper-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	writeEditList(f, verb, repos, now)
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := edit(path); err != nil {
+		return nil, err
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	kept, err := parseEditList(f)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]Repo, len(repos))
+	for _, r := range repos {
+		byKey[strings.ToLower(repoKey(r))] = r
+	}
+	for key := range kept {
+		if _, ok := byKey[key]; !ok {
+			return nil, fmt.Errorf("%s wasn't in the list to %s", key, verb)
+		}
+	}
+
+	edited := []Repo{}
+	for _, r := range repos {
+		if kept[strings.ToLower(repoKey(r))] {
+			edited = append(edited, r)
+		}
+	}
+	return edited, nil
+}
+
+// writeEditList writes the instructions followed by a line per repo, with its
+// last push and URL as a trailing comment
+func writeEditList(w io.Writer, verb string, repos []Repo, now time.Time) {
+	fmt.Fprintf(w, "# Forks to %s, one per line.\n", verb)
+	fmt.Fprintf(w, "# Remove the lines of the forks to keep, the rest are acted on once the\n")
+	fmt.Fprintf(w, "# editor exits. Everything after a '#' is ignored.\n")
+	for _, r := range repos {
+		fmt.Fprintf(w, "%s # pushed %s, %s\n", repoKey(r), formatAge(now, r.PushedAt), r.URL)
+	}
+}
+
+// parseEditList reads the lowercased owner/name keys left in an edited list
+func parseEditList(r io.Reader) (map[string]bool, error) {
+	kept := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			kept[strings.ToLower(line)] = true
+		}
+	}
+	return kept, scanner.Err()
+}
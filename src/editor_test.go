@@ -0,0 +1,102 @@
+package src
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// removeLines returns an editor that drops the lines containing any of the substrings
+func removeLines(substrings ...string) func(path string) error {
+	return func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var kept []string
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			drop := false
+			for _, s := range substrings {
+				drop = drop || strings.Contains(line, s)
+			}
+			if !drop {
+				kept = append(kept, line)
+			}
+		}
+		return os.WriteFile(path, []byte(strings.Join(kept, "")), 0o644)
+	}
+}
+
+func TestEditList(t *testing.T) {
+	t.Parallel()
+	repos := []Repo{newTestRepo("repo-1"), newTestRepo("repo-2"), newTestRepo("repo-3")}
+
+	edited, err := editList(removeLines("repo-2"), "delete", repos, time.Now())
+	if err != nil {
+		t.Fatalf("editList() failed: %v", err)
+	}
+	if len(edited) != 2 || edited[0].Name != "repo-1" || edited[1].Name != "repo-3" {
+		t.Errorf("Expected repo-1 and repo-3, got %v", edited)
+	}
+}
+
+func TestEditList_UnknownRepo(t *testing.T) {
+	t.Parallel()
+	addLine := func(path string) error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString("test-owner/other-repo\n")
+		return err
+	}
+
+	_, err := editList(addLine, "delete", []Repo{newTestRepo("repo-1")}, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "test-owner/other-repo wasn't in the list to delete") {
+		t.Errorf("Expected an error about the added repo, got %v", err)
+	}
+}
+
+func TestParseEditList(t *testing.T) {
+	t.Parallel()
+	input := "# instructions\n\n  Test-Owner/Repo-1 # pushed today\ntest-owner/repo-2\n#test-owner/repo-3\n"
+	kept, err := parseEditList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseEditList() failed: %v", err)
+	}
+	if len(kept) != 2 || !kept["test-owner/repo-1"] || !kept["test-owner/repo-2"] {
+		t.Errorf("Expected repo-1 and repo-2, got %v", kept)
+	}
+}
+
+func TestCLI_InteractiveEdit(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-1", old), newMockFork("stale-2", old)})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withEditFile(removeLines("stale-1")).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--interactive-edit", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/stale-2" {
+		t.Errorf("Expected only stale-2 to be deleted, got %v", *deleted)
+	}
+	if !strings.Contains(stdout.String(), "1 of 2 forks left to delete in the editor") {
+		t.Errorf("Expected the edit to be reported, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,61 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// FetchOptions configures FetchForks. Only Owner and Token are required.
+type FetchOptions struct {
+	Owner string
+	Token string
+
+	// APIURL is the API base URL, defaults to https://api.github.com
+	APIURL string
+
+	// PerPage and MaxPage bound the pagination, both default to 100
+	PerPage int
+	MaxPage int
+
+	// OrgMode lists the forks of an organization via /orgs/{owner}/repos
+	OrgMode bool
+
+	// TokenOwner lists via /user/repos, which includes the private forks of the
+	// user the token belongs to. Ignored in OrgMode.
+	TokenOwner bool
+}
+
+// FetchForks lists the owner's forks. It stops early when ctx is done.
+func FetchForks(ctx context.Context, opts FetchOptions) ([]Repo, error) {
+	if opts.Owner == "" || opts.Token == "" {
+		return nil, errors.New("owner and token are required")
+	}
+	if opts.APIURL == "" {
+		opts.APIURL = defaultBaseURL
+	}
+	if opts.PerPage <= 0 {
+		opts.PerPage = 100
+	}
+	if opts.MaxPage <= 0 {
+		opts.MaxPage = 100
+	}
+
+	endpoint := endpointUsers
+	switch {
+	case opts.OrgMode:
+		endpoint = endpointOrgs
+	case opts.TokenOwner:
+		endpoint = endpointUser
+	}
+
+	return fetchForkedRepos(
+		ctx,                                  // ctx
+		strings.TrimSuffix(opts.APIURL, "/"), // baseURL
+		opts.Owner,                           // owner
+		opts.Token,                           // token
+		endpoint,                             // endpoint
+		opts.PerPage,                         // perPage
+		opts.MaxPage,                         // maxPage
+	)
+}
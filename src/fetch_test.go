@@ -0,0 +1,84 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFetchForks(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		mu.Unlock()
+
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte("[]"))
+			return
+		}
+		json.NewEncoder(w).Encode([]Repo{{Name: "fork", IsFork: true}, {Name: "source"}})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		opts     FetchOptions
+		wantPath string
+	}{
+		{
+			"user",
+			FetchOptions{PerPage: 10},
+			"/users/test-owner/repos?type=forks&page=1&per_page=10",
+		},
+		{
+			"token owner",
+			FetchOptions{TokenOwner: true},
+			"/user/repos?affiliation=owner&page=1&per_page=100",
+		},
+		{
+			"org",
+			FetchOptions{OrgMode: true, TokenOwner: true},
+			"/orgs/test-owner/repos?type=forks&page=1&per_page=100",
+		},
+	}
+
+	for _, tt := range tests {
+		mu.Lock()
+		paths = nil
+		mu.Unlock()
+
+		tt.opts.Owner, tt.opts.Token, tt.opts.APIURL = "test-owner", "test-token", server.URL+"/"
+		repos, err := FetchForks(context.Background(), tt.opts)
+		if err != nil {
+			t.Fatalf("%s: FetchForks() failed: %v", tt.name, err)
+		}
+		if len(repos) != 1 || repos[0].Name != "fork" {
+			t.Errorf("%s: Expected only the fork, got %v", tt.name, repos)
+		}
+		if len(paths) == 0 || paths[0] != tt.wantPath {
+			t.Errorf("%s: Expected first request %q, got %v", tt.name, tt.wantPath, paths)
+		}
+	}
+}
+
+func TestFetchForks_Errors(t *testing.T) {
+	t.Parallel()
+	_, err := FetchForks(context.Background(), FetchOptions{Owner: "test-owner"})
+	if err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("Expected a required options error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := FetchOptions{Owner: "test-owner", Token: "test-token", APIURL: "http://127.0.0.1:1"}
+	if _, err := FetchForks(ctx, opts); err == nil {
+		t.Error("Expected an error with a canceled context")
+	}
+}
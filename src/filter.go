@@ -0,0 +1,237 @@
+package src
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GuardFunc decides whether a repo is kept, and if so why. Consumers of Filter
+// can encode any rule this way, e.g. a star count or a lookup in an inventory.
+type GuardFunc func(r Repo) (guarded bool, reason string)
+
+// FilterOptions configures Filter
+type FilterOptions struct {
+	// Guards are consulted in order and the first one to guard a repo gives
+	// the reason. Nil defaults to DefaultGuards(60).
+	Guards []GuardFunc
+
+	// OnDecision, when set, is called for every repo as soon as it is classified.
+	// The reason of a repo no guard kept is empty.
+	OnDecision func(r Repo, guarded bool, reason string)
+}
+
+// DefaultGuards are the CLI's built-in guards: forks whose name contains any of
+// the names, like --guard, and forks with any activity in the last olderThanDays
+// days, like --older-than-days
+func DefaultGuards(olderThanDays int, names ...string) []GuardFunc {
+	return []GuardFunc{NameGuard(names...), ActivityGuard(olderThanDays)}
+}
+
+// NameGuard guards repos whose name contains any of the names, case-insensitively
+func NameGuard(names ...string) GuardFunc {
+	return nameGuard(guardRulesFromNames(names), false)
+}
+
+// ActivityGuard guards repos created, updated or pushed to in the last days days
+func ActivityGuard(days int) GuardFunc {
+	return activityGuard(filterOptions{olderThanDays: days}, time.Now())
+}
+
+// Filter splits repos into the ones no guard kept, which are safe to delete,
+// and the guarded ones, both in their original order
+func Filter(repos []Repo, opts FilterOptions) (unguarded, guarded []Repo) {
+	guards := opts.Guards
+	if guards == nil {
+		guards = DefaultGuards(60)
+	}
+	noReason := func(r Repo) string { return "" }
+	return splitByGuards(repos, guards, noReason, opts.OnDecision)
+}
+
+// splitByGuards splits repos into the ones no guard kept and the guarded ones.
+// The first guard to keep a repo gives the reason, and deleteReason gives the
+// reason of the others.
+func splitByGuards(
+	repos []Repo,
+	guards []GuardFunc,
+	deleteReason func(r Repo) string,
+	onDecision func(r Repo, guarded bool, reason string)) (unguarded, guarded []Repo) {
+
+	unguarded, guarded = []Repo{}, []Repo{}
+	for _, r := range repos {
+		var isGuarded bool
+		var reason string
+		for _, guard := range guards {
+			if isGuarded, reason = guard(r); isGuarded {
+				break
+			}
+		}
+
+		if isGuarded {
+			guarded = append(guarded, r)
+		} else {
+			unguarded = append(unguarded, r)
+			reason = deleteReason(r)
+		}
+		if onDecision != nil {
+			onDecision(r, isGuarded, reason)
+		}
+	}
+	return unguarded, guarded
+}
+
+// guardWhen turns a check into a GuardFunc giving reason for the repos it keeps
+func guardWhen(check func(r Repo) bool, reason func(r Repo) string) GuardFunc {
+	return func(r Repo) (bool, string) {
+		if !check(r) {
+			return false, ""
+		}
+		return true, reason(r)
+	}
+}
+
+// nameGuard guards repos matching any of the rules, matched against owner/name
+// instead of the name when fullName is set
+func nameGuard(rules []guardRule, fullName bool) GuardFunc {
+	return func(r Repo) (bool, string) {
+		name := r.Name
+		if fullName {
+			name = repoKey(r)
+		}
+		for _, rule := range rules {
+			if rule.matches(name) {
+				return true, rule.describe()
+			}
+		}
+		return false, ""
+	}
+}
+
+// activityCheck reports whether a repo was active since the cutoff, judged as
+// opts configure, along with a description of its activity
+func activityCheck(opts filterOptions, now time.Time) func(r Repo) (bool, string) {
+	cutOffDate := now.Add(time.Duration(-opts.olderThanDays) * 24 * time.Hour)
+
+	return func(repo Repo) (bool, string) {
+		// Only pushes count towards the idle time, replacing the activity check
+		if opts.minIdleDays > 0 {
+			recent := repo.PushedAt.After(now.AddDate(0, 0, -opts.minIdleDays))
+			if repo.PushedAt.IsZero() {
+				return recent, "never pushed"
+			}
+			return recent, fmt.Sprintf("last pushed %s", formatAge(now, repo.PushedAt))
+		}
+
+		// The governing timestamp is the most recent activity of any kind, or
+		// the oldest timestamp when all of them must be recent
+		timed := repo
+		if opts.ageBasis == ageBasisChanges {
+			timed = repo.withoutCreated()
+		}
+		activity := timed.lastActivity()
+		if opts.activityMode == activityModeAll {
+			activity = timed.firstActivity()
+		}
+		return activity.After(cutOffDate), fmt.Sprintf("last active %s", formatAge(now, timed.lastActivity()))
+	}
+}
+
+// activityGuard guards repos active since the cutoff, unless they're orphaned
+// forks to be deleted regardless of age
+func activityGuard(opts filterOptions, now time.Time) GuardFunc {
+	active := activityCheck(opts, now)
+	return func(r Repo) (bool, string) {
+		recent, reason := active(r)
+		if !recent || (opts.deleteOrphans && r.orphaned) {
+			return false, ""
+		}
+		return true, reason
+	}
+}
+
+// builtinGuards are the guards configured by opts, in the order their reasons
+// take precedence. Recent activity outranks the guards that only keep forks
+// the age check would otherwise delete. latest holds the repoKeys of the forks
+// kept by --keep-latest.
+func builtinGuards(opts filterOptions, now time.Time, latest map[string]bool) []GuardFunc {
+	cutOffDate := now.Add(time.Duration(-opts.olderThanDays) * 24 * time.Hour)
+
+	return []GuardFunc{
+		guardWhen(
+			func(r Repo) bool { return opts.includeSources && !r.IsFork },
+			func(r Repo) string { return "source repo" }),
+		nameGuard(opts.guardRules, opts.guardFullName),
+		guardWhen(
+			func(r Repo) bool { return opts.markerFile != "" && r.marked },
+			func(r Repo) string { return fmt.Sprintf("has marker file %s", opts.markerFile) }),
+
+		// Restrict deletion to, or exclude, archived forks
+		guardWhen(
+			func(r Repo) bool {
+				return (opts.onlyArchived && !r.Archived) || (opts.excludeArchived && r.Archived)
+			},
+			func(r Repo) string {
+				if r.Archived {
+					return "archived"
+				}
+				return "not archived"
+			}),
+		guardWhen(
+			func(r Repo) bool { return opts.protectOrphans && r.orphaned },
+			func(r Repo) string { return "orphaned fork" }),
+		guardWhen(
+			func(r Repo) bool { return opts.maxAhead >= 0 && r.compared && r.aheadBy > opts.maxAhead },
+			formatAhead),
+		guardWhen(
+			func(r Repo) bool { return opts.protectOthers && r.compared && r.othersCommitted },
+			func(r Repo) string { return "has commits by others" }),
+		guardWhen(
+			func(r Repo) bool { return opts.protectIssues && r.OpenIssues > 0 },
+			formatOpenIssues),
+
+		// Restrict deletion to forks within the star range
+		guardWhen(
+			func(r Repo) bool {
+				return (opts.minStars >= 0 && r.Stars < opts.minStars) ||
+					(opts.maxStars >= 0 && r.Stars > opts.maxStars)
+			},
+			formatStars),
+		guardWhen(
+			func(r Repo) bool { return opts.maxBranches >= 0 && r.branchCount > opts.maxBranches },
+			func(r Repo) string { return fmt.Sprintf("protected: %d branches", r.branchCount) }),
+		guardWhen(
+			func(r Repo) bool { return opts.pushedWindow.isSet() && !opts.pushedWindow.contains(r.PushedAt) },
+			func(r Repo) string { return formatOutsideWindow(r.PushedAt) }),
+		guardWhen(
+			func(r Repo) bool { return opts.emptyOnly && !r.pristine() },
+			func(r Repo) string {
+				if r.compared {
+					return formatAhead(r)
+				}
+				return "not known to be empty"
+			}),
+
+		// Restrict deletion to forks of repos owned by parentOwner
+		guardWhen(
+			func(r Repo) bool {
+				return opts.parentOwner != "" &&
+					(r.Parent == nil || !strings.EqualFold(r.Parent.Owner.Name, opts.parentOwner))
+			},
+			func(r Repo) string {
+				if r.Parent == nil {
+					return "upstream unknown"
+				}
+				return fmt.Sprintf("upstream owned by %s", r.Parent.Owner.Name)
+			}),
+		activityGuard(opts, now),
+		guardWhen(
+			func(r Repo) bool { return opts.recentBranch && r.branchCommittedAt.After(cutOffDate) },
+			func(r Repo) string {
+				return fmt.Sprintf("default branch committed to %s", formatAge(now, r.branchCommittedAt))
+			}),
+		guardWhen(
+			func(r Repo) bool { return latest[repoKey(r)] },
+			func(r Repo) string { return fmt.Sprintf("among the %d most recently active", opts.keepLatest) }),
+	}
+}
@@ -0,0 +1,95 @@
+package src
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	newRepo := func(name string, ts time.Time, stars int) Repo {
+		r := newTestRepo(name)
+		r.CreatedAt, r.UpdatedAt, r.PushedAt, r.Stars = ts, ts, ts, stars
+		return r
+	}
+	repos := []Repo{
+		newRepo("stale-repo", old, 0),
+		newRepo("active-repo", time.Now(), 0),
+		newRepo("my-dotfiles", old, 0),
+		newRepo("starred-repo", old, 10),
+	}
+	starred := func(r Repo) (bool, string) {
+		return r.Stars > 5, "popular"
+	}
+
+	tests := []struct {
+		name          string
+		opts          FilterOptions
+		wantUnguarded []string
+		wantReasons   map[string]string
+	}{
+		{
+			"default guards",
+			FilterOptions{},
+			[]string{"stale-repo", "my-dotfiles", "starred-repo"},
+			map[string]string{"active-repo": "last active today"},
+		},
+		{
+			"custom guard after the defaults",
+			FilterOptions{Guards: append(DefaultGuards(30, "dotfiles"), starred)},
+			[]string{"stale-repo"},
+			map[string]string{
+				"active-repo":  "last active today",
+				"my-dotfiles":  "guarded by 'dotfiles'",
+				"starred-repo": "popular",
+			},
+		},
+		{
+			"no guards",
+			FilterOptions{Guards: []GuardFunc{}},
+			[]string{"stale-repo", "active-repo", "my-dotfiles", "starred-repo"},
+			map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := map[string]string{}
+			tt.opts.OnDecision = func(r Repo, guarded bool, reason string) {
+				if guarded {
+					reasons[r.Name] = reason
+				}
+			}
+
+			unguarded, guarded := Filter(repos, tt.opts)
+			var names []string
+			for _, r := range unguarded {
+				names = append(names, r.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantUnguarded) {
+				t.Errorf("Expected unguarded %v, got %v", tt.wantUnguarded, names)
+			}
+			if len(guarded)+len(unguarded) != len(repos) {
+				t.Errorf("Expected every repo to be classified, got %d and %d", len(guarded), len(unguarded))
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Errorf("Expected reasons %v, got %v", tt.wantReasons, reasons)
+			}
+		})
+	}
+}
+
+func TestFilter_NoBuiltinGuards(t *testing.T) {
+	t.Parallel()
+	future := time.Now().AddDate(0, 0, 1)
+	r := newTestRepo("future-repo")
+	r.CreatedAt, r.UpdatedAt, r.PushedAt = future, future, future
+
+	// Only the given guards decide, even for timestamps past the built-in cutoff
+	unguarded, guarded := Filter([]Repo{r}, FilterOptions{Guards: []GuardFunc{}})
+	if len(unguarded) != 1 || len(guarded) != 0 {
+		t.Errorf("Expected the repo to be unguarded, got %v and %v", unguarded, guarded)
+	}
+}
@@ -0,0 +1,64 @@
+package src
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ghScriptStdout is the --emit-gh-script path that writes the script to stdout
+const ghScriptStdout = "-"
+
+// writeGHScript writes a shell script deleting repos with gh, one command per
+// repo, for running deletions outside fork-sweeper. Repos on hosts other than
+// github.com are qualified with the host, as gh expects. Every command runs even
+// if some fail, like fork-sweeper's own deletions.
+func writeGHScript(w io.Writer, baseURL, owner string, generatedAt time.Time, repos []Repo) error {
+	host := ghHostFromAPIURL(baseURL)
+	prefix := ""
+	if host != ghDefaultHost {
+		prefix = host + "/"
+	}
+
+	_, err := fmt.Fprintf(w,
+		"#!/bin/sh\n"+
+			"# Deletes the %d forks of %s selected by fork-sweeper at %s.\n"+
+			"# Review it, then run it with a gh token that has the delete_repo scope:\n"+
+			"#     gh auth refresh -h %s -s delete_repo\n",
+		len(repos), owner, generatedAt.Format(time.RFC3339), host)
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		if _, err := fmt.Fprintf(w, "gh repo delete '%s%s' --yes\n", prefix, repoKey(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitGHScript writes the script to path, or to stdout for ghScriptStdout. The
+// file is made executable.
+func emitGHScript(
+	stdout io.Writer,
+	path,
+	baseURL,
+	owner string,
+	generatedAt time.Time,
+	repos []Repo) error {
+
+	if path == ghScriptStdout {
+		return writeGHScript(stdout, baseURL, owner, generatedAt, repos)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if err := writeGHScript(f, baseURL, owner, generatedAt, repos); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
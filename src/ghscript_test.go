@@ -0,0 +1,116 @@
+package src
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteGHScript(t *testing.T) {
+	t.Parallel()
+	generatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repos := []Repo{newTestRepo("repo-1"), newTestRepo("repo-2")}
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		expected []string
+	}{
+		{
+			"github.com",
+			defaultBaseURL,
+			[]string{
+				"gh auth refresh -h github.com -s delete_repo",
+				"gh repo delete 'test-owner/repo-1' --yes\ngh repo delete 'test-owner/repo-2' --yes\n",
+			},
+		},
+		{
+			"enterprise",
+			"https://ghe.example.com/api/v3",
+			[]string{"gh repo delete 'ghe.example.com/test-owner/repo-1' --yes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			if err := writeGHScript(w, tt.baseURL, "test-owner", generatedAt, repos); err != nil {
+				t.Fatalf("writeGHScript() failed: %v", err)
+			}
+			if !strings.HasPrefix(w.String(), "#!/bin/sh\n# Deletes the 2 forks of test-owner") {
+				t.Errorf("Expected a script header, got %q", w.String())
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(w.String(), want) {
+					t.Errorf("Expected %q in the script, got %q", want, w.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCLI_EmitGHScript(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", old), newMockFork("active-repo", time.Now())})
+	path := filepath.Join(t.TempDir(), "delete.sh")
+
+	run := func(scriptPath string) (string, string) {
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		cliConfig := NewCLIConfig(
+			stdout,
+			stderr,
+			"test-version",
+		).withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args := []string{"--owner", "testOwner", "--token", "testToken", "--emit-gh-script", scriptPath}
+		if exitCode := cliConfig.CLI(args); exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	// Writing the script to a file alongside the usual listing
+	if out, _ := run(path); !strings.Contains(out, "Wrote a gh script to delete 1 forks") {
+		t.Errorf("Expected the script to be written, got %q", out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the script to be written: %v", err)
+	}
+	if !strings.Contains(string(data), "/testOwner/stale-repo' --yes") || strings.Contains(string(data), "active-repo") {
+		t.Errorf("Expected only stale-repo in the script, got %q", data)
+	}
+
+	// Writing the script alone to stdout
+	out, _ := run(ghScriptStdout)
+	if !strings.HasPrefix(out, "#!/bin/sh\n") || strings.Contains(out, "Guarded forked repos") {
+		t.Errorf("Expected only the script on stdout, got %q", out)
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("Expected no deletions, got %v", *deleted)
+	}
+}
+
+func TestCLI_EmitGHScriptWithDelete(t *testing.T) {
+	t.Parallel()
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+		withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--emit-gh-script", "-", "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d", exitErr, exitCode)
+	}
+	if !strings.Contains(stderr.String(), "emit-gh-script leaves deleting to gh") {
+		t.Errorf("Expected error message not found in output: %q", stderr)
+	}
+}
@@ -0,0 +1,102 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// How a guard rule's pattern is matched against repo names
+const (
+	matchSubstring = "substring"
+	matchRegex     = "regex"
+	matchGlob      = "glob"
+)
+
+// guardRule protects the repos whose name matches its pattern. Substring and
+// glob patterns match case-insensitively, regular expressions as written.
+type guardRule struct {
+	Pattern string `json:"pattern"`
+	Match   string `json:"match"`
+	Reason  string `json:"reason"`
+
+	re *regexp.Regexp
+}
+
+// guardRulesFromNames turns the --guard names into substring rules
+func guardRulesFromNames(names []string) []guardRule {
+	rules := make([]guardRule, 0, len(names))
+	for _, name := range names {
+		rules = append(rules, guardRule{Pattern: name, Match: matchSubstring})
+	}
+	return rules
+}
+
+// compile validates the rule, defaulting to substring matching
+func (g *guardRule) compile() error {
+	switch g.Match {
+	case "", matchSubstring:
+		g.Match = matchSubstring
+	case matchRegex:
+		re, err := regexp.Compile(g.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regex '%s': %w", g.Pattern, err)
+		}
+		g.re = re
+	case matchGlob:
+		if _, err := path.Match(g.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s': %w", g.Pattern, err)
+		}
+	default:
+		return fmt.Errorf(
+			"match must be '%s', '%s' or '%s', got '%s'",
+			matchSubstring, matchRegex, matchGlob, g.Match)
+	}
+	return nil
+}
+
+func (g guardRule) matches(name string) bool {
+	switch g.Match {
+	case matchRegex:
+		return g.re != nil && g.re.MatchString(name)
+	case matchGlob:
+		ok, _ := path.Match(strings.ToLower(g.Pattern), strings.ToLower(name))
+		return ok
+	default:
+		pattern := strings.ToLower(g.Pattern)
+		return strings.TrimSpace(pattern) != "" && strings.Contains(strings.ToLower(name), pattern)
+	}
+}
+
+// describe explains why a repo matching the rule is guarded
+func (g guardRule) describe() string {
+	if g.Reason != "" {
+		return "protected: " + g.Reason
+	}
+	if g.Match == matchSubstring {
+		return fmt.Sprintf("guarded by '%s'", strings.ToLower(g.Pattern))
+	}
+	return fmt.Sprintf("guarded by %s '%s'", g.Match, g.Pattern)
+}
+
+// readGuardRules reads a JSON array of guard rules from a file
+func readGuardRules(path string) ([]guardRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guard file: %w", err)
+	}
+
+	var rules []guardRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse guard file %s: %w", path, err)
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("guard file %s, rule %d: %w", path, i+1, err)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,177 @@
+package src
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuardRule_Matches(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		rule     guardRule
+		name     string
+		expected bool
+	}{
+		{guardRule{Pattern: "Py", Match: matchSubstring}, "cpython", true},
+		{guardRule{Pattern: " ", Match: matchSubstring}, "cpython", false},
+		{guardRule{Pattern: "^c.*n$", Match: matchRegex}, "cpython", true},
+		{guardRule{Pattern: "^C", Match: matchRegex}, "cpython", false},
+		{guardRule{Pattern: "c*ON", Match: matchGlob}, "cpython", true},
+		{guardRule{Pattern: "py*", Match: matchGlob}, "cpython", false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.rule.compile(); err != nil {
+			t.Fatalf("compile(%+v) failed: %v", tt.rule, err)
+		}
+		if got := tt.rule.matches(tt.name); got != tt.expected {
+			t.Errorf("%s %q matches %q = %v, want %v",
+				tt.rule.Match, tt.rule.Pattern, tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestFilterForkedRepos_GuardFullName(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		newTestRepo("tool"), newTestRepo("other-tool"), newTestRepo("tool"),
+	}
+	forkedRepos[2].Owner.Name = "someorg"
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+	rules := []guardRule{
+		{Pattern: "someorg/*", Match: matchGlob},
+		{Pattern: "test-owner/other-tool", Match: matchSubstring},
+	}
+
+	for _, fullName := range []bool{false, true} {
+		unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+			guardRules:    rules,
+			guardFullName: fullName,
+			olderThanDays: 30,
+		})
+
+		var got []string
+		for _, r := range unguarded {
+			got = append(got, repoKey(r))
+		}
+		expected := []string{"test-owner/tool", "test-owner/other-tool", "someorg/tool"}
+		if fullName {
+			expected = []string{"test-owner/tool"}
+		}
+		if !slices.Equal(got, expected) {
+			t.Errorf("guardFullName %v: expected unguarded %v, got %v", fullName, expected, got)
+		}
+	}
+}
+
+func TestGuardRule_Describe(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		rule     guardRule
+		expected string
+	}{
+		{guardRule{Pattern: "Py", Match: matchSubstring}, "guarded by 'py'"},
+		{guardRule{Pattern: "^py", Match: matchRegex}, "guarded by regex '^py'"},
+		{guardRule{Pattern: "py*", Match: matchGlob, Reason: "compliance-retain"}, "protected: compliance-retain"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.describe(); got != tt.expected {
+			t.Errorf("describe(%+v) = %q, want %q", tt.rule, got, tt.expected)
+		}
+	}
+}
+
+func TestReadGuardRules(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			"valid",
+			`[{"pattern": "^corp-", "match": "regex", "reason": "compliance-retain"},
+			  {"pattern": "dotfiles"}]`,
+			"",
+		},
+		{"invalid json", `{`, "failed to parse guard file"},
+		{"invalid regex", `[{"pattern": "(", "match": "regex"}]`, "rule 1: invalid regex"},
+		{"unknown match", `[{"pattern": "a", "match": "fuzzy"}]`, "match must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			rules, err := readGuardRules(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readGuardRules() failed: %v", err)
+			}
+			if len(rules) != 2 || rules[1].Match != matchSubstring {
+				t.Errorf("Expected two rules with a substring default, got %+v", rules)
+			}
+		})
+	}
+
+	if _, err := readGuardRules(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestCLI_GuardFile(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("corp-tool", old),
+		newMockFork("stale-repo", old),
+	})
+
+	path := filepath.Join(t.TempDir(), "guards.json")
+	rules := `[{"pattern": "corp-*", "match": "glob", "reason": "compliance-retain"}]`
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--guard-file", path, "--delete"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/stale-repo" {
+		t.Errorf("Expected only stale-repo to be deleted, got %v", *deleted)
+	}
+	want := "https://github.com/testOwner/corp-tool (protected: compliance-retain)"
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("Expected %q in output, got %q", want, stdout.String())
+	}
+}
@@ -0,0 +1,40 @@
+package src
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Cap on the random delay before each concurrent deletion starts
+const defaultDeleteJitter = 100 * time.Millisecond
+
+// jitter hands out small random delays that spread out requests launched at the
+// same time. Delays are exponentially distributed, so most are short and a few
+// approach the cap. A nil jitter never delays.
+type jitter struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+	max time.Duration
+}
+
+// newJitter returns delays below max drawn from a source seeded with seed, or
+// nil for max <= 0
+func newJitter(max time.Duration, seed int64) *jitter {
+	if max <= 0 {
+		return nil
+	}
+	return &jitter{rng: rand.New(rand.NewSource(seed)), max: max}
+}
+
+// next returns the next delay, averaging a quarter of the cap
+func (j *jitter) next() time.Duration {
+	if j == nil {
+		return 0
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	d := time.Duration(j.rng.ExpFloat64() * float64(j.max) / 4)
+	return min(d, j.max-1)
+}
@@ -0,0 +1,59 @@
+package src
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+	a, b := newJitter(time.Second, 42), newJitter(time.Second, 42)
+
+	var first, second []time.Duration
+	for range 100 {
+		first = append(first, a.next())
+		second = append(second, b.next())
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same seed to give the same delays")
+	}
+	for _, d := range first {
+		if d < 0 || d >= time.Second {
+			t.Errorf("Expected delays in [0, 1s), got %s", d)
+		}
+	}
+}
+
+func TestJitter_Disabled(t *testing.T) {
+	t.Parallel()
+	j := newJitter(0, 42)
+	if j != nil {
+		t.Fatalf("Expected no jitter for a zero cap")
+	}
+	if d := j.next(); d != 0 {
+		t.Errorf("Expected no delay, got %s", d)
+	}
+}
+
+func TestDeleteRepos_Jittered(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// A cancelled context interrupts the startup delay before any request is made
+	ctx, cancel := context.WithCancel(withRequestConfig(context.Background(), requestConfig{
+		deleteJitter: newJitter(time.Hour, 42),
+	}))
+	cancel()
+
+	result, err := deleteRepos(ctx, server.URL, "test-token", []Repo{newTestRepo("repo-1")})
+	if err == nil || len(result.failed) != 1 {
+		t.Errorf("Expected the delete to be interrupted, got %v", err)
+	}
+}
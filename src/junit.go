@@ -0,0 +1,79 @@
+package src
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+)
+
+// Formats of the --report file
+const (
+	reportFormatJSON  = "json"
+	reportFormatJUnit = "junit"
+)
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// newJUnitSuite turns a report into a JUnit test suite for CI dashboards. Each
+// repo is a test case that passes when it's kept or was deleted, and fails when
+// it would be deleted or its deletion failed.
+func newJUnitSuite(rep report) junitTestSuite {
+	suite := junitTestSuite{
+		Name:      "fork-sweeper " + rep.Owner,
+		Timestamp: rep.GeneratedAt.Format("2006-01-02T15:04:05"),
+		TestCases: []junitTestCase{},
+	}
+
+	for _, e := range rep.Repos {
+		tc := junitTestCase{Name: e.Name, Classname: e.Owner, SystemOut: e.Reason}
+		switch {
+		case e.Error != "":
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("delete failed: %s", e.Error),
+				Type:    "delete-error",
+			}
+		case e.Decision == decisionDelete && !e.Deleted:
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("would delete: %s", e.Reason),
+				Type:    "would-delete",
+			}
+		case e.Deleted:
+			tc.SystemOut = "deleted: " + e.Reason
+		}
+
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	return suite
+}
+
+func writeJUnitReport(path string, rep report) error {
+	data, err := xml.MarshalIndent(newJUnitSuite(rep), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,121 @@
+package src
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewJUnitSuite(t *testing.T) {
+	t.Parallel()
+	kept, pending, deleted, failed := newTestRepo("kept"), newTestRepo("pending"),
+		newTestRepo("deleted"), newTestRepo("failed")
+	reasons := map[string]string{
+		repoKey(kept):    "last active 3d ago",
+		repoKey(pending): "last active 90d ago",
+		repoKey(deleted): "last active 91d ago",
+		repoKey(failed):  "last active 92d ago",
+	}
+	rep := newReport(
+		"test-owner",
+		time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		[]Repo{kept},
+		[]Repo{pending, deleted, failed},
+		[]Repo{deleted},
+		reasons)
+	rep.Repos[3].Error = ErrMsg403
+
+	suite := newJUnitSuite(rep)
+	if suite.Tests != 4 || suite.Failures != 2 || suite.Timestamp != "2024-03-01T12:00:00" {
+		t.Errorf("Unexpected suite totals %+v", suite)
+	}
+
+	expected := []struct {
+		failureType string
+		message     string
+	}{
+		{"", ""},
+		{"would-delete", "would delete: last active 90d ago"},
+		{"", ""},
+		{"delete-error", "delete failed: " + ErrMsg403},
+	}
+	for i, tc := range suite.TestCases {
+		var gotType, gotMessage string
+		if tc.Failure != nil {
+			gotType, gotMessage = tc.Failure.Type, tc.Failure.Message
+		}
+		if gotType != expected[i].failureType || gotMessage != expected[i].message {
+			t.Errorf("Test case %s: expected failure %+v, got %q %q",
+				tc.Name, expected[i], gotType, gotMessage)
+		}
+		if tc.Classname != "test-owner" {
+			t.Errorf("Expected classname test-owner, got %q", tc.Classname)
+		}
+	}
+}
+
+func TestCLI_ReportFormatJUnit(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, _ := newMockGitHubServer(t, []Repo{
+		newMockFork("stale-repo", old),
+		newMockFork("locked-repo", old),
+		newMockFork("active-repo", time.Now()),
+	})
+
+	// Refuse deleting one of the forks
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" && r.URL.Path == "/repos/testOwner/locked-repo" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "report.xml")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(api.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner",
+		"--token", "testToken",
+		"--delete",
+		"--report", path,
+		"--report-format", "junit",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != exitPartial {
+		t.Fatalf("Expected exit code %d, got %d", exitPartial, exitCode)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected a report despite the failure: %v", err)
+	}
+	var suite junitTestSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		t.Fatalf("Invalid JUnit XML: %v", err)
+	}
+
+	failures := map[string]string{}
+	for _, tc := range suite.TestCases {
+		if tc.Failure != nil {
+			failures[tc.Name] = tc.Failure.Type
+		}
+	}
+	if suite.Tests != 3 || len(failures) != 1 || failures["locked-repo"] != "delete-error" {
+		t.Errorf("Expected only locked-repo to fail, got %d tests and %v", suite.Tests, failures)
+	}
+}
@@ -0,0 +1,65 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// markerURL is the contents API URL for path on the fork's default branch
+func markerURL(baseURL string, r Repo, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	u := repoURL(baseURL, r.Owner.Name, r.Name) + "/contents/" + strings.Join(segments, "/")
+	if r.DefaultBranch != "" {
+		u += "?ref=" + url.QueryEscape(r.DefaultBranch)
+	}
+	return u
+}
+
+// fetchMarkers marks every fork that has path on its default branch. A 404,
+// which the contents API also returns for empty forks, leaves a fork unmarked.
+func fetchMarkers(ctx context.Context, baseURL, token, path string, repos []Repo) error {
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	sem := newReadSlots(ctx)
+
+	for i := range repos {
+		wg.Add(1)
+		go func(r *Repo) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+
+			req, err := http.NewRequestWithContext(ctx, "GET", markerURL(baseURL, *r, path), nil)
+			if err == nil {
+				err = doRequest(req, token, nil)
+			}
+			if err != nil && err.Error() == ErrMsg404 {
+				return
+			}
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("checking %s for %s: %w", repoKey(*r), path, err):
+				default:
+				}
+				return
+			}
+			r.marked = true
+		}(&repos[i])
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	if len(errChan) > 0 {
+		return <-errChan
+	}
+	return nil
+}
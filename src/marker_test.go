@@ -0,0 +1,78 @@
+package src
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchMarkers(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/repos/test-owner/kept-repo/contents/docs/KEEP.md" &&
+				r.URL.Query().Get("ref") == "dev" {
+				w.Write([]byte(`{"type": "file"}`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("kept-repo"), newTestRepo("plain-repo")}
+	repos[0].DefaultBranch, repos[1].DefaultBranch = "dev", "dev"
+
+	err := fetchMarkers(context.Background(), server.URL, "test-token", "/docs/KEEP.md", repos)
+	if err != nil {
+		t.Fatalf("fetchMarkers() failed: %v", err)
+	}
+	if !repos[0].marked || repos[1].marked {
+		t.Errorf("Expected only kept-repo to be marked, got %v and %v", repos[0].marked, repos[1].marked)
+	}
+}
+
+func TestFetchMarkers_Error(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("forbidden-repo")}
+	err := fetchMarkers(context.Background(), server.URL, "test-token", ".keep", repos)
+	if err == nil || !strings.Contains(err.Error(), "test-owner/forbidden-repo") {
+		t.Errorf("Expected an error naming the repo, got %v", err)
+	}
+}
+
+func TestFilterForkedRepos_MarkerFile(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "marked-repo", marked: true},
+		{Name: "plain-repo"},
+	}
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		markerFile:    ".keep",
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "plain-repo" {
+		t.Errorf("Expected only plain-repo to be unguarded, got %v", unguarded)
+	}
+	if reasons["marked-repo"] != "has marker file .keep" {
+		t.Errorf("Unexpected reasons %v", reasons)
+	}
+}
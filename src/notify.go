@@ -0,0 +1,98 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// notifyTimeout bounds posting the notification, which outlives the run's ctx
+const notifyTimeout = 10 * time.Second
+
+// notification is the JSON payload posted to the --notify-url webhook. Text and
+// Content carry the same human readable summary so that both Slack and Discord
+// incoming webhooks render it, while the remaining fields serve generic consumers.
+type notification struct {
+	Text         string   `json:"text"`
+	Content      string   `json:"content"`
+	Owner        string   `json:"owner"`
+	Guarded      int      `json:"guarded"`
+	Unguarded    int      `json:"unguarded"`
+	Deleted      int      `json:"deleted"`
+	DeletedRepos []string `json:"deleted_repos,omitempty"`
+	Aborted      string   `json:"aborted,omitempty"`
+}
+
+func newNotification(
+	owner string,
+	guardedRepos,
+	unguardedRepos,
+	deletedRepos []Repo,
+	includeRepos bool) notification {
+
+	text := fmt.Sprintf(
+		"fork-sweeper finished for %s: %d guarded, %d unguarded, %d deleted",
+		owner,
+		len(guardedRepos),
+		len(unguardedRepos),
+		len(deletedRepos))
+
+	n := notification{
+		Text:      text,
+		Content:   text,
+		Owner:     owner,
+		Guarded:   len(guardedRepos),
+		Unguarded: len(unguardedRepos),
+		Deleted:   len(deletedRepos),
+	}
+
+	if includeRepos {
+		for _, r := range deletedRepos {
+			n.DeletedRepos = append(n.DeletedRepos, r.URL)
+		}
+	}
+	return n
+}
+
+// abort marks the notification of a run that refused to delete with the reason
+func (n *notification) abort(reason string) {
+	n.Aborted = reason
+	n.Text = fmt.Sprintf("%s (aborted: %s)", n.Text, reason)
+	n.Content = n.Text
+}
+
+// sendNotification posts the notification to a Slack, Discord or generic webhook.
+// It isn't cancelled along with ctx, so that an interrupted run still reports
+// what it got done, and gives up after notifyTimeout instead.
+func sendNotification(ctx context.Context, url string, n notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	httpClient := httpClientPool.Get().(*http.Client)
+	defer httpClientPool.Put(httpClient)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook request failed with status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewNotification(t *testing.T) {
+	t.Parallel()
+	guarded := []Repo{{Name: "guarded-repo", URL: "https://github.com/test-owner/guarded-repo"}}
+	unguarded := []Repo{{Name: "stale-repo", URL: "https://github.com/test-owner/stale-repo"}}
+
+	n := newNotification("test-owner", guarded, unguarded, unguarded, false)
+	if n.Guarded != 1 || n.Unguarded != 1 || n.Deleted != 1 {
+		t.Errorf("Unexpected counts in notification: %+v", n)
+	}
+	if n.DeletedRepos != nil {
+		t.Errorf("Expected no deleted repos, got %v", n.DeletedRepos)
+	}
+	if n.Text != n.Content || !strings.Contains(n.Text, "1 deleted") {
+		t.Errorf("Unexpected notification text: %q", n.Text)
+	}
+
+	n = newNotification("test-owner", guarded, unguarded, unguarded, true)
+	expected := []string{"https://github.com/test-owner/stale-repo"}
+	if !reflect.DeepEqual(n.DeletedRepos, expected) {
+		t.Errorf("Expected deleted repos %v, got %v", expected, n.DeletedRepos)
+	}
+}
+
+func TestSendNotification(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		responseStatus int
+		wantErr        bool
+	}{
+		{"successful post", http.StatusOK, false},
+		{"webhook error", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got notification
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodPost {
+						t.Errorf("Expected POST method, got %s", r.Method)
+					}
+					if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+						t.Errorf("Failed to decode payload: %v", err)
+					}
+					w.WriteHeader(tt.responseStatus)
+				}))
+			defer server.Close()
+
+			n := notification{Text: "done", Owner: "test-owner", Deleted: 2}
+			err := sendNotification(context.Background(), server.URL, n)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sendNotification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Owner != "test-owner" || got.Deleted != 2 {
+				t.Errorf("Unexpected payload received: %+v", got)
+			}
+		})
+	}
+}
+
+func TestSendNotification_Interrupted(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer server.Close()
+
+	// The run's ctx is cancelled by the interrupt before the notification goes out
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sendNotification(ctx, server.URL, notification{Text: "done"}); err != nil {
+		t.Errorf("Expected the notification to be sent after an interrupt, got %v", err)
+	}
+}
+
+func TestCLI_NotifyFailureOnlyWarns(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+	defer server.Close()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withDeleteRepos(mockDeleteRepos).
+		withFlagErrorHandling(mockFlagErrorHandler).
+		withFilterForkedRepos(mockFilterForkedRepos)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--notify-url", server.URL}
+	exitCode := cliConfig.CLI(args)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "failed to send notification") {
+		t.Errorf("Expected notification warning, got %q", stderr.String())
+	}
+}
+
+func TestCLI_NotifyEveryRun(t *testing.T) {
+	t.Parallel()
+	var (
+		mu   sync.Mutex
+		sent []notification
+	)
+	webhook := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var n notification
+			if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+				t.Errorf("Failed to decode payload: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, n)
+		}))
+	defer webhook.Close()
+
+	old := time.Now().AddDate(-1, 0, 0)
+	tests := []struct {
+		name        string
+		forks       []Repo
+		args        []string
+		wantAborted string
+	}{
+		{"empty sweep", nil, nil, ""},
+		{
+			"max-delete abort",
+			[]Repo{newMockFork("repo-1", old), newMockFork("repo-2", old)},
+			[]string{"--delete", "--max-delete", "1"},
+			"refusing to delete 2 forks, more than max-delete 1",
+		},
+	}
+
+	for _, tt := range tests {
+		server, _ := newMockGitHubServer(t, tt.forks)
+		cliConfig := NewCLIConfig(new(bytes.Buffer), new(bytes.Buffer), "test-version").
+			withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		mu.Lock()
+		sent = nil
+		mu.Unlock()
+		args := append([]string{
+			"--owner", "testOwner",
+			"--token", "testToken",
+			"--notify-url", webhook.URL,
+		}, tt.args...)
+		cliConfig.CLI(args)
+
+		mu.Lock()
+		if len(sent) != 1 || sent[0].Aborted != tt.wantAborted {
+			t.Errorf("%s: expected a notification aborted with %q, got %+v", tt.name, tt.wantAborted, sent)
+		}
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,69 @@
+package src
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formats of the decision listing printed to stdout
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+	outputFormatCSV  = "csv"
+)
+
+// csvHeader is the stable column schema of --output-format csv. Columns are
+// only ever appended so that spreadsheets keyed on position keep working.
+var csvHeader = []string{
+	"name",
+	"url",
+	"owner",
+	"created_at",
+	"updated_at",
+	"pushed_at",
+	"decision",
+	"reason",
+}
+
+// writeCSV writes a header row and one row per repo with RFC3339 timestamps
+func writeCSV(w io.Writer, rep report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range rep.Repos {
+		err := cw.Write([]string{
+			e.Name,
+			e.URL,
+			e.Owner,
+			e.CreatedAt.Format(time.RFC3339),
+			e.UpdatedAt.Format(time.RFC3339),
+			e.PushedAt.Format(time.RFC3339),
+			e.Decision,
+			e.Reason,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeOutput writes the run's decisions in a machine-readable format. JSON
+// uses the same schema as --report.
+func writeOutput(w io.Writer, format string, rep report) error {
+	switch format {
+	case outputFormatCSV:
+		return writeCSV(w, rep)
+	case outputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	default:
+		return fmt.Errorf("unsupported output format '%s'", format)
+	}
+}
@@ -0,0 +1,131 @@
+package src
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestRepo("repo, with comma")
+	r.CreatedAt, r.UpdatedAt, r.PushedAt = ts, ts, ts
+	rep := newReport(
+		"test-owner",
+		ts,
+		nil,
+		[]Repo{r},
+		nil,
+		map[string]string{repoKey(r): "last active 400d ago"})
+
+	buf := new(bytes.Buffer)
+	if err := writeCSV(buf, rep); err != nil {
+		t.Fatalf("writeCSV() failed: %v", err)
+	}
+
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+
+	expected := [][]string{
+		csvHeader,
+		{
+			"repo, with comma",
+			r.URL,
+			"test-owner",
+			"2024-03-01T12:00:00Z",
+			"2024-03-01T12:00:00Z",
+			"2024-03-01T12:00:00Z",
+			decisionDelete,
+			"last active 400d ago",
+		},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+}
+
+func TestWriteOutput_Unsupported(t *testing.T) {
+	t.Parallel()
+	if err := writeOutput(new(bytes.Buffer), "yaml", report{}); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestCLI_OutputFormat(t *testing.T) {
+	t.Parallel()
+	forks := []Repo{
+		newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0)),
+		newMockFork("active-repo", time.Now()),
+	}
+	server, _ := newMockGitHubServer(t, forks)
+
+	tests := []struct {
+		format string
+		parse  func(t *testing.T, out string) []string
+	}{
+		{
+			outputFormatCSV,
+			func(t *testing.T, out string) []string {
+				rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+				if err != nil {
+					t.Fatalf("Expected CSV output, got %q: %v", out, err)
+				}
+				var got []string
+				for _, row := range rows[1:] {
+					got = append(got, row[0]+" "+row[6])
+				}
+				return got
+			},
+		},
+		{
+			outputFormatJSON,
+			func(t *testing.T, out string) []string {
+				var rep report
+				if err := json.Unmarshal([]byte(out), &rep); err != nil {
+					t.Fatalf("Expected JSON output, got %q: %v", out, err)
+				}
+				var got []string
+				for _, e := range rep.Repos {
+					got = append(got, e.Name+" "+e.Decision)
+				}
+				return got
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{
+				"--owner", "testOwner", "--token", "testToken", "--output-format", tt.format,
+			}
+			if exitCode := cliConfig.CLI(args); exitCode != 0 {
+				t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+			}
+
+			expected := []string{"active-repo keep", "stale-repo delete"}
+			if got := tt.parse(t, stdout.String()); !reflect.DeepEqual(got, expected) {
+				t.Errorf("Expected %v, got %v", expected, got)
+			}
+			if !strings.Contains(stderr.String(), "Fetching forked repositories") {
+				t.Errorf("Expected progress on stderr, got %q", stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package src
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// loginPattern matches GitHub logins: letters, digits and single hyphens
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:-?[A-Za-z0-9])*$`)
+
+// parseOwner returns the login of an owner given by name or pasted as the URL
+// of its profile, org page or one of its repos, e.g. https://github.com/rednafi,
+// github.com/orgs/my-org/repositories or https://ghe.example.com/my-org/
+func parseOwner(s string) (string, error) {
+	owner := strings.TrimPrefix(strings.TrimSpace(s), "@")
+	if strings.Contains(owner, "/") {
+		raw := owner
+		if !strings.Contains(raw, "://") {
+			raw = "https://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid owner URL '%s': %w", s, err)
+		}
+
+		segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+		if len(segments) > 1 && (segments[0] == "orgs" || segments[0] == "users") {
+			segments = segments[1:]
+		}
+		if len(segments) == 0 {
+			return "", fmt.Errorf("no owner in URL '%s'", s)
+		}
+		owner = segments[0]
+	}
+
+	if !loginPattern.MatchString(owner) {
+		return "", fmt.Errorf("invalid owner '%s'", s)
+	}
+	return owner, nil
+}
@@ -0,0 +1,94 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseOwner(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"rednafi", "rednafi"},
+		{" @rednafi ", "rednafi"},
+		{"https://github.com/rednafi", "rednafi"},
+		{"https://github.com/rednafi/", "rednafi"},
+		{"http://www.github.com/rednafi?tab=repositories", "rednafi"},
+		{"github.com/rednafi", "rednafi"},
+		{"https://github.com/rednafi/fork-sweeper/pulls", "rednafi"},
+		{"https://github.com/orgs/my-org", "my-org"},
+		{"https://github.com/orgs/my-org/repositories", "my-org"},
+		{"https://github.com/users/rednafi/projects/1", "rednafi"},
+		{"https://ghe.example.com/my-org/", "my-org"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOwner(tt.input)
+		if err != nil {
+			t.Errorf("parseOwner(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseOwner(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseOwner_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{"https://github.com/", "no owner in URL"},
+		{"https://github.com/red_nafi", "invalid owner"},
+		{"red nafi", "invalid owner"},
+		{"-rednafi", "invalid owner"},
+		{"https://github.com/%zz", "invalid owner URL"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseOwner(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parseOwner(%q) error = %v, want %q", tt.input, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCLI_OwnerURL(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	var gotOwner string
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(func(
+			ctx context.Context,
+			baseURL,
+			owner,
+			token,
+			endpoint string,
+			perPage,
+			maxPage int) ([]Repo, error) {
+
+			gotOwner = owner
+			return nil, nil
+		}).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "https://github.com/testOwner/", "--token", "testToken"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if gotOwner != "testOwner" {
+		t.Errorf("Expected the owner to be parsed out of the URL, got %q", gotOwner)
+	}
+}
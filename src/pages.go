@@ -0,0 +1,98 @@
+package src
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pageCollector gathers listing pages that may be fetched concurrently and
+// completed in any order. Pages are keyed by their 1-based number so that the
+// merged result is always in listing order.
+type pageCollector struct {
+	mu    sync.Mutex
+	first int // number of the first page of the listing
+	pages map[int][]Repo
+
+	// Pages that failed in best-effort mode, merged past instead of ending the listing
+	failed map[int]error
+}
+
+// newPageCollector collects the pages of a listing starting at page first
+func newPageCollector(first int) *pageCollector {
+	return &pageCollector{first: first, pages: map[int][]Repo{}, failed: map[int]error{}}
+}
+
+// add records the repos of a page. It's safe to call from multiple goroutines.
+func (c *pageCollector) add(pageNum int, repos []Repo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pages[pageNum] = repos
+}
+
+// fail records a page that couldn't be fetched. It's safe to call from multiple
+// goroutines.
+func (c *pageCollector) fail(pageNum int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failed[pageNum] = err
+}
+
+// incomplete returns an error describing the failed pages, or nil if there are none
+func (c *pageCollector) incomplete() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.failed) == 0 {
+		return nil
+	}
+	return &incompleteListingError{failed: maps.Clone(c.failed)}
+}
+
+// repos merges the collected pages in page order, starting at the first. It stops
+// at the first missing page since anything past a gap can't be told apart from
+// a page past the end of the listing. Pages left empty by dropping non-forks
+// don't end it, and failed pages are skipped over.
+func (c *pageCollector) repos() []Repo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var all []Repo
+	for n := c.first; ; n++ {
+		if _, failed := c.failed[n]; failed {
+			continue
+		}
+		repos, ok := c.pages[n]
+		if !ok {
+			return all
+		}
+		all = append(all, repos...)
+	}
+}
+
+// incompleteListingError is returned along with the repos of a best-effort
+// listing when some of its pages couldn't be fetched
+type incompleteListingError struct {
+	failed map[int]error
+}
+
+// pages returns the numbers of the failed pages in order
+func (e *incompleteListingError) pages() []int {
+	pages := make([]int, 0, len(e.failed))
+	for n := range e.failed {
+		pages = append(pages, n)
+	}
+	slices.Sort(pages)
+	return pages
+}
+
+func (e *incompleteListingError) Error() string {
+	pages := e.pages()
+	nums := make([]string, len(pages))
+	for i, n := range pages {
+		nums[i] = strconv.Itoa(n)
+	}
+	return fmt.Sprintf("incomplete listing, failed to fetch page %s", strings.Join(nums, ", "))
+}
@@ -0,0 +1,96 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPageCollector_ShuffledCompletion(t *testing.T) {
+	t.Parallel()
+	const numPages, perPage = 20, 5
+
+	page := func(n int) []Repo {
+		repos := make([]Repo, perPage)
+		for i := range repos {
+			repos[i] = Repo{Name: fmt.Sprintf("repo-%d-%d", n, i)}
+		}
+		return repos
+	}
+
+	for run := 0; run < 10; run++ {
+		c := newPageCollector(1)
+
+		// Complete the pages, plus an empty page past the end, in a random order
+		var wg sync.WaitGroup
+		for _, i := range rand.Perm(numPages + 1) {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				if n > numPages {
+					c.add(n, nil)
+					return
+				}
+				c.add(n, page(n))
+			}(i + 1)
+		}
+		wg.Wait()
+
+		got := c.repos()
+		if len(got) != numPages*perPage {
+			t.Fatalf("Expected %d repos, got %d", numPages*perPage, len(got))
+		}
+		for i, r := range got {
+			want := fmt.Sprintf("repo-%d-%d", i/perPage+1, i%perPage)
+			if r.Name != want {
+				t.Fatalf("Expected repo %d to be %q, got %q", i, want, r.Name)
+			}
+		}
+	}
+}
+
+func TestPageCollector_EmptyPage(t *testing.T) {
+	t.Parallel()
+	c := newPageCollector(1)
+	c.add(3, []Repo{{Name: "third"}})
+	c.add(1, []Repo{{Name: "first"}})
+	c.add(2, nil) // only non-forks on the page
+
+	got := c.repos()
+	if len(got) != 2 || got[0].Name != "first" || got[1].Name != "third" {
+		t.Errorf("Expected the pages around the empty one, got %v", got)
+	}
+}
+
+func TestPageCollector_StopsAtGap(t *testing.T) {
+	t.Parallel()
+	c := newPageCollector(1)
+	c.add(1, []Repo{{Name: "first"}})
+	c.add(3, []Repo{{Name: "third"}})
+
+	got := c.repos()
+	if len(got) != 1 || got[0].Name != "first" {
+		t.Errorf("Expected the merge to stop at the missing page, got %v", got)
+	}
+}
+
+func TestPageCollector_SkipsFailed(t *testing.T) {
+	t.Parallel()
+	c := newPageCollector(1)
+	c.add(1, []Repo{{Name: "first"}})
+	c.fail(2, errors.New("boom"))
+	c.add(3, []Repo{{Name: "third"}})
+
+	got := c.repos()
+	if len(got) != 2 || got[1].Name != "third" {
+		t.Errorf("Expected the merge to skip the failed page, got %v", got)
+	}
+
+	var incomplete *incompleteListingError
+	if err := c.incomplete(); !errors.As(err, &incomplete) || !reflect.DeepEqual(incomplete.pages(), []int{2}) {
+		t.Errorf("Expected page 2 to be reported as failed, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Default number of concurrent requests fetching repo details and comparisons
+const defaultReadConcurrency = 10
+
+// repoParent is the upstream a fork was created from
+type repoParent struct {
+	FullName      string `json:"full_name"`
+	URL           string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         struct {
+		Name string `json:"login"`
+	} `json:"owner"`
+}
+
+// fetchRepo fetches a single repo which, unlike the listing endpoints, includes
+// the parent of a fork
+func fetchRepo(ctx context.Context, baseURL, owner, name, token string) (Repo, error) {
+	var r Repo
+
+	url := repoURL(baseURL, owner, name)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return r, err
+	}
+
+	err = doRequest(req, token, &r)
+	return r, err
+}
+
+// fetchParents fills in the parent of every fork in place. Forks that come back
+// without a parent, e.g. because their upstream was deleted, are marked orphaned.
+func fetchParents(ctx context.Context, baseURL, token string, repos []Repo) error {
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	sem := newReadSlots(ctx)
+
+	for i := range repos {
+		wg.Add(1)
+		go func(r *Repo) {
+			defer wg.Done()
+			sem.acquire()
+			defer sem.release()
+
+			details, err := fetchRepo(ctx, baseURL, r.Owner.Name, r.Name, token)
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("fetching %s: %w", repoKey(*r), err):
+				default:
+				}
+				return
+			}
+			r.Parent = details.Parent
+			r.orphaned = r.IsFork && details.Parent == nil
+		}(&repos[i])
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	if len(errChan) > 0 {
+		return <-errChan
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchParents(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/test-owner/parented-repo":
+				fmt.Fprintln(
+					w,
+					`{"name": "parented-repo", "fork": true,`+
+						`"parent": {"full_name": "upstream/parented-repo",`+
+						`"html_url": "https://github.com/upstream/parented-repo",`+
+						`"default_branch": "main",`+
+						`"owner": {"login": "upstream"}}}`)
+			case "/repos/test-owner/orphaned-repo":
+				fmt.Fprintln(w, `{"name": "orphaned-repo", "fork": true}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("parented-repo"), newTestRepo("orphaned-repo")}
+	for i := range repos {
+		repos[i].IsFork = true
+	}
+
+	if err := fetchParents(context.Background(), server.URL, "test-token", repos); err != nil {
+		t.Fatalf("fetchParents() failed: %v", err)
+	}
+
+	if repos[0].Parent == nil ||
+		repos[0].Parent.FullName != "upstream/parented-repo" ||
+		repos[0].Parent.Owner.Name != "upstream" ||
+		repos[0].Parent.DefaultBranch != "main" ||
+		repos[0].orphaned {
+		t.Errorf("Expected parented-repo to have its parent, got %+v", repos[0])
+	}
+	if repos[1].Parent != nil || !repos[1].orphaned {
+		t.Errorf("Expected orphaned-repo to be orphaned, got %+v", repos[1])
+	}
+
+	missing := []Repo{newTestRepo("missing-repo")}
+	err := fetchParents(context.Background(), server.URL, "test-token", missing)
+	if err == nil || !strings.Contains(err.Error(), "test-owner/missing-repo") {
+		t.Errorf("Expected an error naming the repo, got %v", err)
+	}
+}
+
+func TestCLI_ProtectOrphans(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	parented := newMockFork("parented-repo", old)
+	parented.Parent = &repoParent{FullName: "upstream/parented-repo"}
+	server, deleted := newMockGitHubServer(
+		t, []Repo{parented, newMockFork("orphaned-repo", old)})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--protect-orphans", "--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/parented-repo" {
+		t.Errorf("Expected only parented-repo to be deleted, got %v", *deleted)
+	}
+	if !strings.Contains(stderr.String(), "orphaned-repo has no parent data") {
+		t.Errorf("Expected an orphan warning, got %q", stderr.String())
+	}
+}
+
+func TestCLI_DeleteOrphans(t *testing.T) {
+	t.Parallel()
+	parented := newMockFork("parented-repo", time.Now())
+	parented.Parent = &repoParent{FullName: "upstream/parented-repo"}
+	server, deleted := newMockGitHubServer(
+		t, []Repo{parented, newMockFork("orphaned-repo", time.Now())})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--delete-orphans", "--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/orphaned-repo" {
+		t.Errorf("Expected only orphaned-repo to be deleted, got %v", *deleted)
+	}
+
+	expected := "Orphaned forked repos [upstream deleted]:\n" +
+		"    - https://github.com/testOwner/orphaned-repo\n"
+	if !strings.Contains(stdout.String(), expected) {
+		t.Errorf("Expected an orphaned section, got %q", stdout.String())
+	}
+}
+
+func TestCLI_OrphanFlagsExclusive(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--protect-orphans", "--delete-orphans",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "mutually exclusive") {
+		t.Errorf("Expected a mutually exclusive error, got %q", stderr.String())
+	}
+}
+
+func TestFilterForkedRepos_ParentOwner(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forkedRepos := []Repo{
+		{Name: "react", Parent: &repoParent{FullName: "facebook/react"}},
+		{Name: "gson", Parent: &repoParent{FullName: "google/gson"}},
+		{Name: "orphaned-repo"},
+	}
+	forkedRepos[0].Parent.Owner.Name = "facebook"
+	forkedRepos[1].Parent.Owner.Name = "google"
+	for i := range forkedRepos {
+		forkedRepos[i].CreatedAt, forkedRepos[i].UpdatedAt, forkedRepos[i].PushedAt = old, old, old
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		parentOwner:   "facebook",
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 1 || unguarded[0].Name != "react" {
+		t.Errorf("Expected only react to be unguarded, got %v", unguarded)
+	}
+	if reasons["gson"] != "upstream owned by google" ||
+		reasons["orphaned-repo"] != "upstream unknown" {
+		t.Errorf("Unexpected reasons %v", reasons)
+	}
+}
+
+func TestCLI_ParentOwner(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	react := newMockFork("react", old)
+	react.Parent = &repoParent{FullName: "facebook/react"}
+	react.Parent.Owner.Name = "facebook"
+	gson := newMockFork("gson", old)
+	gson.Parent = &repoParent{FullName: "google/gson"}
+	gson.Parent.Owner.Name = "google"
+	server, deleted := newMockGitHubServer(
+		t, []Repo{react, gson, newMockFork("orphaned-repo", old)})
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--parent-owner", "Facebook", "--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/react" {
+		t.Errorf("Expected only react to be deleted, got %v", *deleted)
+	}
+}
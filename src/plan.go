@@ -0,0 +1,90 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// plan is the exact set of forks a listing run selected for deletion, written by
+// --plan-file and deleted by --apply
+type plan struct {
+	Owner       string      `json:"owner"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Repos       []planEntry `json:"repos"`
+}
+
+type planEntry struct {
+	ID    int64  `json:"id,omitempty"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+
+	// When the repo was last pushed to as of an export read by readDecisions
+	pushedAt time.Time
+}
+
+func newPlan(owner string, generatedAt time.Time, repos []Repo) plan {
+	p := plan{Owner: owner, GeneratedAt: generatedAt, Repos: []planEntry{}}
+	for _, r := range repos {
+		p.Repos = append(p.Repos, planEntry{ID: r.ID, Owner: r.Owner.Name, Name: r.Name, URL: r.URL})
+	}
+	return p
+}
+
+func writePlan(path string, p plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readPlan(path string) (plan, error) {
+	var p plan
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// verifyPlan fetches every planned repo again and returns the ones that still
+// exist as forks. Repos that were deleted or aren't forks are skipped with a
+// warning, so applying a stale plan never touches anything it didn't list. So
+// are repos pushed to since an export that recorded their last push, and repos
+// whose ID changed, i.e. that were deleted and recreated under the same name.
+func verifyPlan(ctx context.Context, w io.Writer, baseURL, token string, p plan) ([]Repo, error) {
+	var repos []Repo
+	for _, e := range p.Repos {
+		r, err := fetchRepo(ctx, baseURL, e.Owner, e.Name, token)
+		if err != nil {
+			if err.Error() == ErrMsg404 {
+				fmt.Fprintf(w, "Warning: %s/%s no longer exists, skipping\n", e.Owner, e.Name)
+				continue
+			}
+			return nil, fmt.Errorf("verifying %s/%s: %w", e.Owner, e.Name, err)
+		}
+		if !r.IsFork {
+			fmt.Fprintf(w, "Warning: %s/%s is not a fork, skipping\n", e.Owner, e.Name)
+			continue
+		}
+		if e.ID != 0 && r.ID != 0 && r.ID != e.ID {
+			fmt.Fprintf(w, "Warning: %s/%s was recreated since the plan, skipping\n", e.Owner, e.Name)
+			continue
+		}
+		if !e.pushedAt.IsZero() && r.PushedAt.After(e.pushedAt) {
+			fmt.Fprintf(w, "Warning: %s/%s was pushed to since the export, skipping\n", e.Owner, e.Name)
+			continue
+		}
+		repos = append(repos, r)
+	}
+	return repos, nil
+}
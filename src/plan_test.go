@@ -0,0 +1,186 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlan_RoundTrip(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "plan.json")
+	generatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := newPlan("test-owner", generatedAt, []Repo{newTestRepo("repo-1"), newTestRepo("repo-2")})
+
+	if err := writePlan(path, p); err != nil {
+		t.Fatalf("writePlan() failed: %v", err)
+	}
+	got, err := readPlan(path)
+	if err != nil {
+		t.Fatalf("readPlan() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Expected %+v, got %+v", p, got)
+	}
+}
+
+func TestVerifyPlan(t *testing.T) {
+	t.Parallel()
+	source := newMockFork("source-repo", time.Now())
+	source.IsFork = false
+	stale := newMockFork("stale-repo", time.Now())
+	stale.ID = 1
+	recreated := newMockFork("recreated-repo", time.Now())
+	recreated.ID = 3
+	server, _ := newMockGitHubServer(t, []Repo{stale, source, recreated})
+
+	p := plan{Owner: "testOwner", Repos: []planEntry{
+		{ID: 1, Owner: "testOwner", Name: "stale-repo"},
+		{Owner: "testOwner", Name: "source-repo"},
+		{Owner: "testOwner", Name: "gone-repo"},
+		{ID: 2, Owner: "testOwner", Name: "recreated-repo"},
+	}}
+	var warnings bytes.Buffer
+	repos, err := verifyPlan(context.Background(), &warnings, server.URL, "testToken", p)
+	if err != nil {
+		t.Fatalf("verifyPlan() failed: %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].Name != "stale-repo" {
+		t.Errorf("Expected only stale-repo to be verified, got %v", repos)
+	}
+	for _, want := range []string{
+		"testOwner/source-repo is not a fork",
+		"testOwner/gone-repo no longer exists",
+		"testOwner/recreated-repo was recreated since the plan",
+	} {
+		if !strings.Contains(warnings.String(), want) {
+			t.Errorf("Expected a warning %q, got %q", want, warnings.String())
+		}
+	}
+}
+
+func TestCLI_PlanAndApply(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("stale-repo", old),
+		newMockFork("active-repo", time.Now()),
+	})
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	run := func(args ...string) string {
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+
+		cliConfig := NewCLIConfig(
+			stdout,
+			stderr,
+			"test-version",
+		).withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args = append([]string{"--owner", "testOwner", "--token", "testToken"}, args...)
+		if exitCode := cliConfig.CLI(args); exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	// Planning lists the forks without deleting them
+	if out := run("--plan-file", path); !strings.Contains(out, "Wrote a plan to delete 1 forks") {
+		t.Errorf("Expected the plan to be written, got %q", out)
+	}
+	if len(*deleted) != 0 {
+		t.Fatalf("Expected nothing to be deleted while planning, got %v", *deleted)
+	}
+
+	// Applying deletes exactly the planned forks
+	if out := run("--apply", path); !strings.Contains(out, "Deleted 1 fork ") {
+		t.Errorf("Expected the plan to be applied, got %q", out)
+	}
+	if len(*deleted) != 1 || (*deleted)[0] != "testOwner/stale-repo" {
+		t.Errorf("Expected only stale-repo to be deleted, got %v", *deleted)
+	}
+}
+
+func TestCLI_ApplyOwnerMismatch(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := writePlan(path, newPlan("otherUser", time.Now(), nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--apply", path}
+	if exitCode := cliConfig.CLI(args); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "plan is for otherUser, not testOwner") {
+		t.Errorf("Expected an owner mismatch error, got %q", stderr.String())
+	}
+}
+
+func TestCLI_ApplyLimits(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	forks := []Repo{newMockFork("repo-1", old), newMockFork("repo-2", old)}
+	server, deleted := newMockGitHubServer(t, forks)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.json")
+	reportPath := filepath.Join(dir, "report.json")
+	if err := writePlan(path, newPlan("testOwner", time.Now(), forks)); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (int, string) {
+		stderr := new(bytes.Buffer)
+		cliConfig := NewCLIConfig(
+			new(bytes.Buffer),
+			stderr,
+			"test-version",
+		).withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args = append([]string{"--owner", "testOwner", "--token", "testToken", "--apply", path}, args...)
+		return cliConfig.CLI(args), stderr.String()
+	}
+
+	// Applying a plan larger than max-delete is refused like any other run
+	exitCode, errOut := run("--max-delete", "1")
+	if exitCode != exitErr || !strings.Contains(errOut, "refusing to delete 2 forks, more than max-delete 1") {
+		t.Errorf("Expected the plan to be refused, got %d: %q", exitCode, errOut)
+	}
+	if len(*deleted) != 0 {
+		t.Fatalf("Expected nothing to be deleted, got %v", *deleted)
+	}
+
+	// Passing --yes goes through, and the deletions are reported
+	if exitCode, errOut := run("--max-delete", "1", "--yes", "--report", reportPath); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, errOut)
+	}
+	if len(*deleted) != 2 {
+		t.Errorf("Expected both planned forks to be deleted, got %v", *deleted)
+	}
+	rep, err := readReport(reportPath)
+	if err != nil {
+		t.Fatalf("readReport() failed: %v", err)
+	}
+	if len(rep.Repos) != 2 || !rep.Repos[0].Deleted || !rep.Repos[1].Deleted {
+		t.Errorf("Expected the planned forks reported as deleted, got %+v", rep.Repos)
+	}
+}
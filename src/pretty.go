@@ -0,0 +1,61 @@
+package src
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// ANSI escape codes used by --pretty
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+)
+
+// isTerminal reports whether w is a character device like an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatSize renders a repo size, which GitHub reports in kilobytes
+func formatSize(kb int) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%.1f MB", float64(kb)/1024)
+	default:
+		return fmt.Sprintf("%d KB", kb)
+	}
+}
+
+// writePrettyGroup writes repos under a header as aligned name, last push and size
+// columns, coloring the header when color is set. A positive limit truncates the
+// rows while the header still counts all repos.
+func writePrettyGroup(
+	w io.Writer, title string, repos []Repo, now time.Time, color string, limit int) {
+
+	if color != "" {
+		fmt.Fprintf(w, "\n%s%s%s (%d)%s\n", ansiBold, color, title, len(repos), ansiReset)
+	} else {
+		fmt.Fprintf(w, "\n%s (%d)\n", title, len(repos))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "    NAME\tLAST PUSH\tSIZE")
+	shown, more := truncateList(repos, limit)
+	for _, r := range shown {
+		fmt.Fprintf(tw, "    %s\t%s\t%s\n", r.Name, formatAge(now, r.PushedAt), formatSize(r.Size))
+	}
+	tw.Flush()
+	writeMore(w, more)
+}
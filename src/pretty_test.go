@@ -0,0 +1,100 @@
+package src
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		kb       int
+		expected string
+	}{
+		{0, "0 KB"},
+		{512, "512 KB"},
+		{1536, "1.5 MB"},
+		{3 * 1024 * 1024, "3.0 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatSize(tt.kb); got != tt.expected {
+			t.Errorf("formatSize(%d) = %q, want %q", tt.kb, got, tt.expected)
+		}
+	}
+}
+
+func TestWritePrettyGroup(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	short, long := newTestRepo("cli"), newTestRepo("a-much-longer-name")
+	short.PushedAt, short.Size = now.AddDate(0, 0, -3), 2048
+	long.PushedAt, long.Size = now.AddDate(0, 0, -400), 12
+
+	var buf bytes.Buffer
+	writePrettyGroup(&buf, "Guarded", []Repo{short, long}, now, "", 0)
+
+	expected := "\nGuarded (2)\n" +
+		"    NAME                LAST PUSH  SIZE\n" +
+		"    cli                 3d ago     2.0 MB\n" +
+		"    a-much-longer-name  400d ago   12 KB\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	buf.Reset()
+	writePrettyGroup(&buf, "Guarded", []Repo{short, long}, now, "", 1)
+	if got := buf.String(); !strings.HasPrefix(got, "\nGuarded (2)\n") ||
+		!strings.HasSuffix(got, "    ... and 1 more\n") {
+		t.Errorf("Expected a truncated list counting both repos, got %q", got)
+	}
+
+	buf.Reset()
+	writePrettyGroup(&buf, "Guarded", nil, now, ansiGreen, 0)
+	if !strings.HasPrefix(buf.String(), "\n"+ansiBold+ansiGreen+"Guarded (0)"+ansiReset) {
+		t.Errorf("Expected a colored header, got %q", buf.String())
+	}
+}
+
+func TestCLI_Pretty(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		terminal   bool
+		wantOutput string
+	}{
+		{"terminal", true, "Unguarded forked repos [will be deleted] (1)"},
+		{"not a terminal", false, "Unguarded forked repos [will be deleted]:\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newMockGitHubServer(
+				t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler).
+				withLookupEnv(func(key string) (string, bool) { return "", key == "NO_COLOR" }).
+				withIsTerminal(func(w io.Writer) bool { return tt.terminal })
+
+			args := []string{"--owner", "testOwner", "--token", "testToken", "--pretty"}
+			if exitCode := cliConfig.CLI(args); exitCode != 0 {
+				t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got %q", tt.wantOutput, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package src
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quotaTracker follows the X-RateLimit headers of the responses of a run to
+// tell how much of the hourly rate limit it used. Requests of a run can finish
+// out of order and span a reset, so the remaining counts are tracked per rate
+// limit resource and window. A nil tracker ignores everything.
+type quotaTracker struct {
+	mu      sync.Mutex
+	windows map[quotaWindow]*quotaRange
+	core    quotaWindow // latest window of the core resource
+}
+
+// quotaWindow is a rate limit resource, e.g. "core" or "search", until its reset
+type quotaWindow struct {
+	resource string
+	reset    int64
+}
+
+// quotaRange is the highest and lowest remaining count seen in a window
+type quotaRange struct {
+	high, low int
+}
+
+// rateLimitUsage is what a run used of the rate limit and what's left of the
+// core resource's
+type rateLimitUsage struct {
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{windows: map[quotaWindow]*quotaRange{}}
+}
+
+// observe records the rate limit headers of a response, if it has them
+func (q *quotaTracker) observe(resp *http.Response) {
+	if q == nil || resp == nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w := quotaWindow{resource: resource, reset: reset}
+	r, ok := q.windows[w]
+	if !ok {
+		q.windows[w] = &quotaRange{high: remaining, low: remaining}
+	} else {
+		r.high, r.low = max(r.high, remaining), min(r.low, remaining)
+	}
+	if resource == "core" && reset >= q.core.reset {
+		q.core = w
+	}
+}
+
+// usage sums up the requests counted against the rate limit, the first one of
+// each window included, or returns nil if no response had the headers
+func (q *quotaTracker) usage() *rateLimitUsage {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.windows) == 0 {
+		return nil
+	}
+	u := &rateLimitUsage{}
+	for _, r := range q.windows {
+		u.Used += r.high - r.low + 1
+	}
+	if core, ok := q.windows[q.core]; ok {
+		u.Remaining = core.low
+		u.Reset = time.Unix(q.core.reset, 0)
+	}
+	return u
+}
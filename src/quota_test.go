@@ -0,0 +1,106 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func rateLimitResponse(resource string, remaining int, reset int64) *http.Response {
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+	if resource != "" {
+		h.Set("X-RateLimit-Resource", resource)
+	}
+	return &http.Response{Header: h}
+}
+
+func TestQuotaTracker(t *testing.T) {
+	t.Parallel()
+	q := newQuotaTracker()
+	if q.usage() != nil {
+		t.Fatal("Expected no usage before any response")
+	}
+
+	// Out of order within a window, then a reset and a search request
+	q.observe(rateLimitResponse("", 4998, 1000))
+	q.observe(rateLimitResponse("core", 4999, 1000))
+	q.observe(rateLimitResponse("core", 4995, 1000))
+	q.observe(rateLimitResponse("core", 5000, 2000))
+	q.observe(rateLimitResponse("core", 4999, 2000))
+	q.observe(rateLimitResponse("search", 29, 1060))
+	q.observe(&http.Response{Header: http.Header{}})
+	q.observe(nil)
+
+	expected := rateLimitUsage{Used: 5 + 2 + 1, Remaining: 4999, Reset: time.Unix(2000, 0)}
+	if got := q.usage(); got == nil || *got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	var nilTracker *quotaTracker
+	nilTracker.observe(rateLimitResponse("core", 1, 1))
+	if nilTracker.usage() != nil {
+		t.Error("Expected a nil tracker to report no usage")
+	}
+}
+
+func TestCLI_RateLimitUsage(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, _ := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", old), newMockFork("active-repo", time.Now())})
+
+	var remaining atomic.Int64
+	remaining.Store(5000)
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining.Add(-1), 10))
+		w.Header().Set("X-RateLimit-Reset", "1900000000")
+		handler.ServeHTTP(w, r)
+	})
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--delete", "--summary-json-to", path,
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	used := int(5000 - remaining.Load())
+	want := "Used ~" + strconv.Itoa(used) + " API requests, " +
+		strconv.FormatInt(remaining.Load(), 10) + " remaining until " +
+		time.Unix(1900000000, 0).Format(time.RFC3339)
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("Expected %q in output, got %q", want, stdout.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the summary to be written: %v", err)
+	}
+	var got summary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid summary %q: %v", data, err)
+	}
+	if got.RateLimit == nil || got.RateLimit.Used != used {
+		t.Errorf("Expected the summary to count %d requests, got %+v", used, got.RateLimit)
+	}
+}
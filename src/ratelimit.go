@@ -0,0 +1,41 @@
+package src
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces operations evenly to cap their throughput, independently
+// of how many run concurrently. The first operation goes through immediately.
+// A nil limiter is unlimited.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter allows n operations per period, or returns nil for n <= 0
+func newRateLimiter(n int, period time.Duration) *rateLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: period / time.Duration(n)}
+}
+
+// wait blocks until the next operation is allowed or the context is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	at := time.Now()
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, time.Until(at))
+}
@@ -0,0 +1,69 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	t.Parallel()
+	l := newRateLimiter(5, 100*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait() failed: %v", err)
+		}
+	}
+
+	// The first operation is immediate, the other four are 20ms apart
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Expected the operations to be spaced out, took %s", elapsed)
+	}
+
+	var unlimited *rateLimiter
+	if newRateLimiter(0, time.Minute) != nil || unlimited.wait(context.Background()) != nil {
+		t.Error("Expected a nil limiter to be unlimited")
+	}
+}
+
+func TestRateLimiter_Canceled(t *testing.T) {
+	t.Parallel()
+	l := newRateLimiter(1, time.Hour)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("Expected the first operation to go through, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+}
+
+func TestDeleteRepos_RateLimited(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := withRequestConfig(context.Background(), requestConfig{
+		deleteLimiter: newRateLimiter(4, 100*time.Millisecond),
+	})
+	repos := []Repo{
+		newTestRepo("repo-1"), newTestRepo("repo-2"), newTestRepo("repo-3"), newTestRepo("repo-4"),
+	}
+
+	start := time.Now()
+	if _, err := deleteRepos(ctx, server.URL, "test-token", repos); err != nil {
+		t.Fatalf("deleteRepos() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 75*time.Millisecond {
+		t.Errorf("Expected the concurrent deletes to be throttled, took %s", elapsed)
+	}
+}
@@ -0,0 +1,217 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Decisions recorded for each repo
+const (
+	decisionKeep   = "keep"
+	decisionDelete = "delete"
+)
+
+type reportEntry struct {
+	ID        int64     `json:"id,omitempty"`
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	PushedAt  time.Time `json:"pushed_at"`
+	Decision  string    `json:"decision"`
+	Reason    string    `json:"reason,omitempty"`
+	Deleted   bool      `json:"deleted"`
+	Error     string    `json:"error,omitempty"` // why the deletion failed
+
+	// Upstream compare page, when the fork's parent was fetched
+	CompareURL string `json:"compare_url,omitempty"`
+}
+
+// report is the manifest of a run's decisions written by --report
+type report struct {
+	Owner       string        `json:"owner"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Repos       []reportEntry `json:"repos"`
+
+	// Aborted says why the run refused to delete, e.g. over --max-delete
+	Aborted string `json:"aborted,omitempty"`
+}
+
+// repoKey identifies a repo across runs by its full name, derived from its
+// owner and name for repos the API didn't send one for
+func repoKey(r Repo) string {
+	if r.FullName != "" {
+		return r.FullName
+	}
+	return r.Owner.Name + "/" + r.Name
+}
+
+func newReportEntry(r Repo, decision, reason string) reportEntry {
+	return reportEntry{
+		ID:        r.ID,
+		Name:      r.Name,
+		Owner:     r.Owner.Name,
+		URL:       r.URL,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		PushedAt:  r.PushedAt,
+		Decision:  decision,
+		Reason:    reason,
+
+		CompareURL: compareURL(r),
+	}
+}
+
+// newReport builds the manifest of a run. The reasons are keyed by repoKey.
+func newReport(
+	owner string,
+	generatedAt time.Time,
+	guardedRepos,
+	unguardedRepos,
+	deletedRepos []Repo,
+	reasons map[string]string) report {
+
+	deleted := make(map[string]bool, len(deletedRepos))
+	for _, r := range deletedRepos {
+		deleted[repoKey(r)] = true
+	}
+
+	rep := report{Owner: owner, GeneratedAt: generatedAt, Repos: []reportEntry{}}
+	for _, r := range guardedRepos {
+		rep.Repos = append(rep.Repos, newReportEntry(r, decisionKeep, reasons[repoKey(r)]))
+	}
+	for _, r := range unguardedRepos {
+		entry := newReportEntry(r, decisionDelete, reasons[repoKey(r)])
+		entry.Deleted = deleted[repoKey(r)]
+		rep.Repos = append(rep.Repos, entry)
+	}
+	return rep
+}
+
+func writeReport(path string, rep report) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readReport(path string) (report, error) {
+	var rep report
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rep, err
+	}
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return rep, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return rep, nil
+}
+
+type reportChange struct {
+	entry            reportEntry
+	previousDecision string
+}
+
+// reportDiff lists what changed between two runs: forks that appeared, forks that
+// disappeared and forks whose decision flipped
+type reportDiff struct {
+	added   []reportEntry
+	removed []reportEntry
+	changed []reportChange
+}
+
+func (d reportDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+func entryKey(e reportEntry) string {
+	return e.Owner + "/" + e.Name
+}
+
+// hasIDs reports whether every entry of rep records its repo ID, which reports
+// written before IDs were recorded don't
+func hasIDs(rep report) bool {
+	for _, e := range rep.Repos {
+		if e.ID == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// diffReports matches the entries of the reports by repo ID when both record
+// it, so that renamed forks aren't reported as gone and new, and by owner/name
+// otherwise
+func diffReports(previous, current report) reportDiff {
+	var diff reportDiff
+
+	key := entryKey
+	if hasIDs(previous) && hasIDs(current) {
+		key = func(e reportEntry) string { return strconv.FormatInt(e.ID, 10) }
+	}
+
+	previousEntries := make(map[string]reportEntry, len(previous.Repos))
+	for _, e := range previous.Repos {
+		previousEntries[key(e)] = e
+	}
+
+	currentKeys := make(map[string]bool, len(current.Repos))
+	for _, e := range current.Repos {
+		currentKeys[key(e)] = true
+
+		prev, ok := previousEntries[key(e)]
+		switch {
+		case !ok:
+			diff.added = append(diff.added, e)
+		case prev.Decision != e.Decision:
+			diff.changed = append(diff.changed, reportChange{entry: e, previousDecision: prev.Decision})
+		}
+	}
+
+	for _, e := range previous.Repos {
+		if !currentKeys[key(e)] {
+			diff.removed = append(diff.removed, e)
+		}
+	}
+	return diff
+}
+
+// printDiffSince prints what changed in current since the report at path
+func printDiffSince(w io.Writer, path string, current report) error {
+	previous, err := readReport(path)
+	if err != nil {
+		return err
+	}
+	printReportDiff(w, previous, diffReports(previous, current))
+	return nil
+}
+
+func printReportDiff(w io.Writer, previous report, diff reportDiff) {
+	since := previous.GeneratedAt.Format(time.RFC3339)
+	if diff.empty() {
+		fmt.Fprintf(w, "\nNo changes since the previous run (%s)\n", since)
+		return
+	}
+
+	fmt.Fprintf(w, "\nChanges since the previous run (%s):\n", since)
+	for _, e := range diff.added {
+		fmt.Fprintf(w, "    + %s (new, %s)\n", e.URL, e.Decision)
+	}
+	for _, c := range diff.changed {
+		fmt.Fprintf(w, "    ~ %s (%s -> %s)\n", c.entry.URL, c.previousDecision, c.entry.Decision)
+	}
+	for _, e := range diff.removed {
+		status := "gone"
+		if e.Deleted {
+			status = "deleted"
+		}
+		fmt.Fprintf(w, "    - %s (%s)\n", e.URL, status)
+	}
+}
@@ -0,0 +1,232 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRepo(name string) Repo {
+	r := Repo{Name: name, URL: "https://github.com/test-owner/" + name}
+	r.Owner.Name = "test-owner"
+	return r
+}
+
+func TestNewReport(t *testing.T) {
+	t.Parallel()
+	guarded := []Repo{newTestRepo("kept-repo")}
+	unguarded := []Repo{newTestRepo("stale-repo"), newTestRepo("failed-repo")}
+	reasons := map[string]string{
+		"test-owner/kept-repo":  "guarded by 'kept'",
+		"test-owner/stale-repo": "last active 90d ago",
+	}
+
+	rep := newReport("test-owner", time.Now(), guarded, unguarded, unguarded[:1], reasons)
+
+	expected := []struct {
+		name     string
+		decision string
+		reason   string
+		deleted  bool
+	}{
+		{"kept-repo", decisionKeep, "guarded by 'kept'", false},
+		{"stale-repo", decisionDelete, "last active 90d ago", true},
+		{"failed-repo", decisionDelete, "", false},
+	}
+
+	if len(rep.Repos) != len(expected) {
+		t.Fatalf("Expected %d report entries, got %d", len(expected), len(rep.Repos))
+	}
+	for i, e := range rep.Repos {
+		if e.Name != expected[i].name ||
+			e.Decision != expected[i].decision ||
+			e.Reason != expected[i].reason ||
+			e.Deleted != expected[i].deleted {
+			t.Errorf("Expected report entry %+v, got %+v", expected[i], e)
+		}
+	}
+}
+
+func TestRepoKey(t *testing.T) {
+	t.Parallel()
+	r := newTestRepo("repo-1")
+	if got := repoKey(r); got != "test-owner/repo-1" {
+		t.Errorf("Expected the key derived from owner and name, got %q", got)
+	}
+
+	var decoded Repo
+	data := `{"id": 42, "full_name": "Test-Owner/repo-1", "name": "repo-1", "owner": {"login": "Test-Owner"}}`
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != 42 || repoKey(decoded) != "Test-Owner/repo-1" {
+		t.Errorf("Expected the API's id and full name, got %d and %q", decoded.ID, repoKey(decoded))
+	}
+}
+
+func TestWriteReadReport(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "report.json")
+	generatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rep := newReport(
+		"test-owner", generatedAt, nil, []Repo{newTestRepo("stale-repo")}, nil, nil)
+
+	if err := writeReport(path, rep); err != nil {
+		t.Fatalf("writeReport() failed: %v", err)
+	}
+	got, err := readReport(path)
+	if err != nil {
+		t.Fatalf("readReport() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, rep) {
+		t.Errorf("Expected report %+v, got %+v", rep, got)
+	}
+
+	if _, err := readReport(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error reading a missing report")
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	t.Parallel()
+	previous := newReport(
+		"test-owner",
+		time.Now(),
+		[]Repo{newTestRepo("kept-repo"), newTestRepo("flipped-repo")},
+		[]Repo{newTestRepo("deleted-repo"), newTestRepo("gone-repo")},
+		[]Repo{newTestRepo("deleted-repo")},
+		nil)
+	current := newReport(
+		"test-owner",
+		time.Now(),
+		[]Repo{newTestRepo("kept-repo")},
+		[]Repo{newTestRepo("flipped-repo"), newTestRepo("new-repo")},
+		nil,
+		nil)
+
+	diff := diffReports(previous, current)
+
+	if len(diff.added) != 1 || diff.added[0].Name != "new-repo" {
+		t.Errorf("Expected new-repo to be added, got %+v", diff.added)
+	}
+	if len(diff.changed) != 1 ||
+		diff.changed[0].entry.Name != "flipped-repo" ||
+		diff.changed[0].previousDecision != decisionKeep {
+		t.Errorf("Expected flipped-repo to change from keep, got %+v", diff.changed)
+	}
+	if len(diff.removed) != 2 {
+		t.Errorf("Expected 2 removed repos, got %+v", diff.removed)
+	}
+
+	w := new(bytes.Buffer)
+	printReportDiff(w, previous, diff)
+	for _, line := range []string{
+		"+ https://github.com/test-owner/new-repo (new, delete)",
+		"~ https://github.com/test-owner/flipped-repo (keep -> delete)",
+		"- https://github.com/test-owner/deleted-repo (deleted)",
+		"- https://github.com/test-owner/gone-repo (gone)",
+	} {
+		if !strings.Contains(w.String(), line) {
+			t.Errorf("Expected diff output to contain %q, got %q", line, w.String())
+		}
+	}
+
+	w.Reset()
+	printReportDiff(w, current, diffReports(current, current))
+	if !strings.Contains(w.String(), "No changes since the previous run") {
+		t.Errorf("Expected no changes, got %q", w.String())
+	}
+}
+
+func TestDiffReports_Renamed(t *testing.T) {
+	t.Parallel()
+	withID := func(name string, id int64) Repo {
+		r := newTestRepo(name)
+		r.ID = id
+		return r
+	}
+	previous := newReport(
+		"test-owner", time.Now(), nil, []Repo{withID("old-name", 1), withID("flipped-repo", 2)}, nil, nil)
+	current := newReport(
+		"test-owner", time.Now(), []Repo{withID("flipped-repo", 2)}, []Repo{withID("new-name", 1)}, nil, nil)
+
+	// Matched by ID, the renamed fork is neither gone nor new
+	diff := diffReports(previous, current)
+	if len(diff.added) != 0 || len(diff.removed) != 0 {
+		t.Errorf("Expected the rename to be matched, got added %+v and removed %+v", diff.added, diff.removed)
+	}
+	if len(diff.changed) != 1 || diff.changed[0].entry.Name != "flipped-repo" {
+		t.Errorf("Expected flipped-repo to change, got %+v", diff.changed)
+	}
+
+	// Without IDs in the previous report, the entries are matched by name
+	for i := range previous.Repos {
+		previous.Repos[i].ID = 0
+	}
+	diff = diffReports(previous, current)
+	if len(diff.added) != 1 || diff.added[0].Name != "new-name" ||
+		len(diff.removed) != 1 || diff.removed[0].Name != "old-name" {
+		t.Errorf("Expected the rename to be matched by name, got added %+v and removed %+v",
+			diff.added, diff.removed)
+	}
+}
+
+func TestCLI_ReportAndDiff(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "report.json")
+	old := time.Now().AddDate(-1, 0, 0)
+
+	run := func(forks []Repo, args ...string) string {
+		server, _ := newMockGitHubServer(t, forks)
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+
+		cliConfig := NewCLIConfig(
+			stdout,
+			stderr,
+			"test-version",
+		).withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args = append([]string{"--owner", "testOwner", "--token", "testToken"}, args...)
+		if exitCode := cliConfig.CLI(args); exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	run([]Repo{newMockFork("stale-repo", old)}, "--report", path)
+
+	rep, err := readReport(path)
+	if err != nil {
+		t.Fatalf("readReport() failed: %v", err)
+	}
+	if len(rep.Repos) != 1 || rep.Repos[0].Reason == "" {
+		t.Errorf("Expected one report entry with a reason, got %+v", rep.Repos)
+	}
+
+	output := run(
+		[]Repo{newMockFork("stale-repo", old), newMockFork("new-repo", old)},
+		"--dry-run-diff", path)
+	if !strings.Contains(output, "+ https://github.com/testOwner/new-repo (new, delete)") {
+		t.Errorf("Expected new-repo in the diff, got %q", output)
+	}
+
+	// An empty listing is diffed and reported too, so the next run compares
+	// against it rather than the stale report
+	output = run(nil, "--dry-run-diff", path, "--report", path)
+	if !strings.Contains(output, "- https://github.com/testOwner/stale-repo (gone)") {
+		t.Errorf("Expected stale-repo to be gone, got %q", output)
+	}
+	if rep, err := readReport(path); err != nil || len(rep.Repos) != 0 {
+		t.Errorf("Expected an empty report, got %+v, %v", rep, err)
+	}
+	output = run(nil, "--dry-run-diff", path)
+	if !strings.Contains(output, "No changes since the previous run") {
+		t.Errorf("Expected no changes, got %q", output)
+	}
+}
@@ -0,0 +1,96 @@
+package src
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appendedCSVHeader is the column schema of a CSV audit log written by
+// --report-append: the run, then the columns of --output-format csv, then the
+// outcome of the deletion
+var appendedCSVHeader = append(append([]string{"run_id", "generated_at"}, csvHeader...), "deleted", "error")
+
+// appendedEntry is a line of a JSON Lines audit log written by --report-append
+type appendedEntry struct {
+	RunID       string    `json:"run_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	reportEntry
+}
+
+// appendReport appends the report's entries to the audit log at path, tagged
+// with runID so that runs can be told apart. Logs ending in .csv are CSV with a
+// header written when the log is created, anything else is JSON Lines. The
+// entries of a run go out in a single append, so runs writing to the same log
+// on a local filesystem don't interleave.
+func appendReport(path, runID string, rep report) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = writeAppendedCSV(&buf, runID, rep, info.Size() == 0)
+	} else {
+		err = writeAppendedJSONL(&buf, runID, rep)
+	}
+	if err == nil {
+		_, err = f.Write(buf.Bytes())
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeAppendedJSONL(buf *bytes.Buffer, runID string, rep report) error {
+	enc := json.NewEncoder(buf)
+	for _, e := range rep.Repos {
+		if err := enc.Encode(appendedEntry{runID, rep.GeneratedAt, e}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAppendedCSV(buf *bytes.Buffer, runID string, rep report, header bool) error {
+	cw := csv.NewWriter(buf)
+	if header {
+		if err := cw.Write(appendedCSVHeader); err != nil {
+			return err
+		}
+	}
+	for _, e := range rep.Repos {
+		err := cw.Write([]string{
+			runID,
+			rep.GeneratedAt.Format(time.RFC3339),
+			e.Name,
+			e.URL,
+			e.Owner,
+			e.CreatedAt.Format(time.RFC3339),
+			e.UpdatedAt.Format(time.RFC3339),
+			e.PushedAt.Format(time.RFC3339),
+			e.Decision,
+			e.Reason,
+			strconv.FormatBool(e.Deleted),
+			e.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
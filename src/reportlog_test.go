@@ -0,0 +1,178 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readAppendedJSONL(t *testing.T, path string) []appendedEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []appendedEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e appendedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAppendReport_JSONL(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	generatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rep := newReport(
+		"test-owner", generatedAt, []Repo{newTestRepo("kept-repo")}, []Repo{newTestRepo("stale-repo")}, nil, nil)
+
+	for _, runID := range []string{"run-1", "run-2"} {
+		if err := appendReport(path, runID, rep); err != nil {
+			t.Fatalf("appendReport() failed: %v", err)
+		}
+	}
+
+	entries := readAppendedJSONL(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	if entries[0].RunID != "run-1" || entries[3].RunID != "run-2" {
+		t.Errorf("Expected entries tagged by run, got %+v", entries)
+	}
+	if entries[1].Name != "stale-repo" || entries[1].Decision != decisionDelete ||
+		!entries[1].GeneratedAt.Equal(generatedAt) {
+		t.Errorf("Unexpected entry %+v", entries[1])
+	}
+}
+
+func TestAppendReport_CSV(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.csv")
+	rep := newReport("test-owner", time.Now(), nil, []Repo{newTestRepo("stale-repo")}, nil, nil)
+	rep.Repos[0].Error = "API request failed with status: 403"
+
+	for _, runID := range []string{"run-1", "run-2"} {
+		if err := appendReport(path, runID, rep); err != nil {
+			t.Fatalf("appendReport() failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV %q: %v", data, err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(appendedCSVHeader, ",") {
+		t.Fatalf("Expected a single header and 2 rows, got %q", rows)
+	}
+	if rows[1][0] != "run-1" || rows[2][0] != "run-2" || rows[2][2] != "stale-repo" ||
+		rows[2][len(rows[2])-1] != "API request failed with status: 403" {
+		t.Errorf("Unexpected rows %q", rows[1:])
+	}
+}
+
+func TestAppendReport_Concurrent(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	repos := make([]Repo, 50)
+	for i := range repos {
+		repos[i] = newTestRepo(fmt.Sprintf("repo-%d", i))
+	}
+	rep := newReport("test-owner", time.Now(), repos, nil, nil, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(runID string) {
+			defer wg.Done()
+			if err := appendReport(path, runID, rep); err != nil {
+				t.Errorf("appendReport() failed: %v", err)
+			}
+		}(fmt.Sprintf("run-%d", i))
+	}
+	wg.Wait()
+
+	// Every line parses, and each run's entries are contiguous
+	entries := readAppendedJSONL(t, path)
+	if len(entries) != 500 {
+		t.Fatalf("Expected 500 entries, got %d", len(entries))
+	}
+	for i := 0; i < len(entries); i += len(repos) {
+		for _, e := range entries[i : i+len(repos)] {
+			if e.RunID != entries[i].RunID {
+				t.Fatalf("Expected the entries of %s to be contiguous, found %s", entries[i].RunID, e.RunID)
+			}
+		}
+	}
+}
+
+func TestCLI_ReportAppend(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, _ := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", old), newMockFork("active-repo", time.Now())})
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	for i := 0; i < 2; i++ {
+		stderr := new(bytes.Buffer)
+		cliConfig := NewCLIConfig(
+			new(bytes.Buffer),
+			stderr,
+			"test-version",
+		).withBaseURL(server.URL).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args := []string{"--owner", "testOwner", "--token", "testToken", "--report", path, "--report-append"}
+		if exitCode := cliConfig.CLI(args); exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+		}
+	}
+
+	entries := readAppendedJSONL(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("Expected the entries of both runs, got %d", len(entries))
+	}
+	if entries[0].RunID == "" || entries[0].RunID == entries[2].RunID {
+		t.Errorf("Expected a distinct run ID per run, got %q and %q", entries[0].RunID, entries[2].RunID)
+	}
+}
+
+func TestCLI_ReportAppendInvalid(t *testing.T) {
+	t.Parallel()
+	for _, args := range [][]string{
+		{"--report-append"},
+		{"--report-append", "--report", "audit.xml", "--report-format", "junit"},
+	} {
+		stderr := new(bytes.Buffer)
+		cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+			withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+			withFetchForkedRepos(mockFetchForkedRepos).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args = append([]string{"--owner", "testOwner", "--token", "testToken"}, args...)
+		if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+			t.Errorf("Expected exit code %d for %v, got %d", exitErr, args, exitCode)
+		}
+		if !strings.Contains(stderr.String(), "report-append requires report") {
+			t.Errorf("Expected error message not found in output: %q", stderr)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Default delay before the first retry, doubled on every subsequent attempt
+const defaultRetryDelay = time.Second
+
+// maxRetryDelay caps the wait before a retry, however many attempts were made
+// or however long a Retry-After header asks for
+const maxRetryDelay = time.Minute
+
+// retryBudget caps the total number of retries across a run. It's shared by every
+// goroutine making requests, so a flaky network can't multiply the request count.
+// A nil budget is unlimited.
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+func newRetryBudget(n int) *retryBudget {
+	b := &retryBudget{}
+	b.remaining.Store(int64(n))
+	return b
+}
+
+// take consumes a retry from the budget, reporting whether one was available
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	return b.remaining.Add(-1) >= 0
+}
+
+// shouldRetry reports whether a request failed transiently: a network error that
+// isn't due to the context being done, a server error, a 429, or a 403 due to
+// the secondary rate limit
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return resp.StatusCode >= http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		isSecondaryRateLimit(resp)
+}
+
+// errSecondaryRateLimit is returned for 403s that are due to GitHub's secondary
+// rate limit rather than missing permissions, after backing off didn't help
+var errSecondaryRateLimit = errors.New("secondary rate limit exceeded")
+
+// rateLimitError is returned instead of waiting out a rate limit when the run
+// aborts on rate limits. reset is when the limit lifts, zero when unknown.
+type rateLimitError struct {
+	reset time.Time
+}
+
+func (e *rateLimitError) Error() string {
+	if e.reset.IsZero() {
+		return "rate limit exceeded"
+	}
+	return fmt.Sprintf("rate limit exceeded, resets at %s", e.reset.Format(time.RFC3339))
+}
+
+// isRateLimited reports whether resp was refused by the primary rate limit,
+// a 403 or 429 with no requests remaining, or by the secondary rate limit
+func isRateLimited(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	return isSecondaryRateLimit(resp)
+}
+
+// newRateLimitError reads when the limit lifts from the Retry-After header, or
+// the X-RateLimit-Reset header otherwise
+func newRateLimitError(resp *http.Response) *rateLimitError {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return &rateLimitError{reset: time.Now().Add(time.Duration(seconds) * time.Second).Round(time.Second)}
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil && reset > 0 {
+		return &rateLimitError{reset: time.Unix(reset, 0)}
+	}
+	return &rateLimitError{}
+}
+
+// Longest response body read to tell a secondary rate limit from a permission error
+const maxRateLimitBodyBytes = 64 << 10
+
+// isSecondaryRateLimit reports whether a 403 is GitHub's secondary rate limit,
+// which comes with a Retry-After header or says so in its message. The body is
+// left intact for whoever reads it next.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if resp.Header.Get("Retry-After") != "" {
+		return true
+	}
+
+	body, err := peekBody(resp, maxRateLimitBodyBytes)
+	return err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// peekBody reads up to n bytes of the response body and puts them back, so that
+// the body is left intact for whoever reads it next
+func peekBody(resp *http.Response, n int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	return body, err
+}
+
+// retryDelay is how long to wait before retrying resp: as long as its
+// Retry-After header asks, or the backoff otherwise, up to maxRetryDelay
+func retryDelay(resp *http.Response, backoff time.Duration) time.Duration {
+	if resp == nil {
+		return min(backoff, maxRetryDelay)
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return min(backoff, maxRetryDelay)
+	}
+	if seconds > int(maxRetryDelay/time.Second) {
+		return maxRetryDelay
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// backoffDelay is the delay before the first retry doubled for every attempt
+// since, up to maxRetryDelay. It stops doubling at the cap, so that many
+// attempts can't overflow the delay.
+func backoffDelay(delay time.Duration, attempt int) time.Duration {
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// sleepContext waits for d or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
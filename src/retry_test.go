@@ -0,0 +1,288 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_Take(t *testing.T) {
+	t.Parallel()
+	b := newRetryBudget(2)
+	if !b.take() || !b.take() {
+		t.Fatal("Expected the first two retries to be available")
+	}
+	if b.take() {
+		t.Error("Expected the budget to be exhausted")
+	}
+
+	var unlimited *retryBudget
+	if !unlimited.take() {
+		t.Error("Expected a nil budget to be unlimited")
+	}
+}
+
+func TestRetryBudget_Concurrent(t *testing.T) {
+	t.Parallel()
+	b := newRetryBudget(10)
+
+	var wg sync.WaitGroup
+	var taken atomic.Int64
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.take() {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if taken.Load() != 10 {
+		t.Errorf("Expected 10 retries to be taken, got %d", taken.Load())
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	t.Parallel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		status   int
+		err      error
+		expected bool
+	}{
+		{"network error", context.Background(), 0, errors.New("connection reset"), true},
+		{"canceled context", canceled, 0, context.Canceled, false},
+		{"server error", context.Background(), http.StatusBadGateway, nil, true},
+		{"too many requests", context.Background(), http.StatusTooManyRequests, nil, true},
+		{"not found", context.Background(), http.StatusNotFound, nil, false},
+		{"ok", context.Background(), http.StatusOK, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			if got := shouldRetry(tt.ctx, resp, tt.err); got != tt.expected {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDoRequest_Retries(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		retries      int
+		budget       int
+		wantErr      bool
+		wantRequests int64
+	}{
+		{"no retries", 0, 10, true, 1},
+		{"recovers after retries", 3, 10, false, 3},
+		{"too few retries", 1, 10, true, 2},
+		{"budget exhausted", 3, 1, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Fail the first two requests with a server error
+			var requests atomic.Int64
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if requests.Add(1) <= 2 {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					w.Write([]byte("{}"))
+				}))
+			defer server.Close()
+
+			ctx := withRequestConfig(context.Background(), requestConfig{
+				retries:    tt.retries,
+				retryDelay: time.Millisecond,
+				budget:     newRetryBudget(tt.budget),
+			})
+			req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+			var result map[string]any
+			err := doRequest(req, "test-token", &result)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("doRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests.Load() != tt.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tt.wantRequests, requests.Load())
+			}
+		})
+	}
+}
+
+func TestDoRequest_SecondaryRateLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		retryAfter   string
+		body         string
+		retries      int
+		wantErr      string
+		wantRequests int64
+	}{
+		{"permission", "", `{"message": "Must have admin rights"}`, 3, ErrMsg403, 1},
+		{"message", "", `{"message": "You have exceeded a secondary rate limit."}`, 3, "", 3},
+		{"retry after", "0", `{"message": "Forbidden"}`, 3, "", 3},
+		{"still limited", "0", `{"message": "Forbidden"}`, 1, errSecondaryRateLimit.Error(), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Forbid the first two requests
+			var requests atomic.Int64
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if requests.Add(1) <= 2 {
+						if tt.retryAfter != "" {
+							w.Header().Set("Retry-After", tt.retryAfter)
+						}
+						w.WriteHeader(http.StatusForbidden)
+						w.Write([]byte(tt.body))
+						return
+					}
+					w.Write([]byte("{}"))
+				}))
+			defer server.Close()
+
+			var log bytes.Buffer
+			ctx := withRequestConfig(context.Background(), requestConfig{
+				retries:      tt.retries,
+				retryDelay:   time.Millisecond,
+				errorBodyLog: &log,
+			})
+			req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+			var result map[string]any
+			err := doRequest(req, "test-token", &result)
+			if gotErr := fmt.Sprint(err); (err != nil || tt.wantErr != "") && gotErr != tt.wantErr {
+				t.Errorf("doRequest() error = %v, want %q", err, tt.wantErr)
+			}
+			if requests.Load() != tt.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tt.wantRequests, requests.Load())
+			}
+			// The body read to detect the rate limit is still logged in full
+			if err != nil && !strings.Contains(log.String(), tt.body) {
+				t.Errorf("Expected the body %s to be logged, got %q", tt.body, log.String())
+			}
+		})
+	}
+}
+
+func TestDoRequest_AbortOnRateLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		status       int
+		headers      map[string]string
+		wantErr      string
+		wantRequests int64
+	}{
+		{
+			"primary",
+			http.StatusForbidden,
+			map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000000"},
+			"rate limit exceeded, resets at " + time.Unix(1700000000, 0).Format(time.RFC3339),
+			1,
+		},
+		{"too many requests", http.StatusTooManyRequests, nil, "rate limit exceeded", 1},
+		{"permission", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "10"}, ErrMsg403, 1},
+		{"server error", http.StatusBadGateway, nil, "", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Refuse the first request only
+			var requests atomic.Int64
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if requests.Add(1) == 1 {
+						for k, v := range tt.headers {
+							w.Header().Set(k, v)
+						}
+						w.WriteHeader(tt.status)
+						return
+					}
+					w.Write([]byte("{}"))
+				}))
+			defer server.Close()
+
+			ctx := withRequestConfig(context.Background(), requestConfig{
+				retries:          3,
+				retryDelay:       time.Millisecond,
+				abortOnRateLimit: true,
+			})
+			req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+			var result map[string]any
+			err := doRequest(req, "test-token", &result)
+			if gotErr := fmt.Sprint(err); (err != nil || tt.wantErr != "") && gotErr != tt.wantErr {
+				t.Errorf("doRequest() error = %v, want %q", err, tt.wantErr)
+			}
+			if requests.Load() != tt.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tt.wantRequests, requests.Load())
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+	resp := &http.Response{Header: http.Header{}}
+	if got := retryDelay(resp, time.Second); got != time.Second {
+		t.Errorf("Expected the backoff without Retry-After, got %s", got)
+	}
+	resp.Header.Set("Retry-After", "30")
+	if got := retryDelay(resp, time.Second); got != 30*time.Second {
+		t.Errorf("Expected Retry-After to win, got %s", got)
+	}
+	if got := retryDelay(nil, time.Second); got != time.Second {
+		t.Errorf("Expected the backoff for network errors, got %s", got)
+	}
+	resp.Header.Set("Retry-After", "86400")
+	if got := retryDelay(resp, time.Second); got != maxRetryDelay {
+		t.Errorf("Expected Retry-After clamped to %s, got %s", maxRetryDelay, got)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{5, 32 * time.Second},
+		{6, maxRetryDelay},
+		{100, maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDelay(time.Second, tt.attempt); got != tt.expected {
+			t.Errorf("Expected %s for attempt %d, got %s", tt.expected, tt.attempt, got)
+		}
+	}
+}
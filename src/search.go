@@ -0,0 +1,121 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// The search API only ever returns the first 1000 results of a query
+const maxSearchResults = 1000
+
+type searchResult struct {
+	TotalCount        int    `json:"total_count"`
+	IncompleteResults bool   `json:"incomplete_results"`
+	Items             []Repo `json:"items"`
+}
+
+// searchQuery scopes the user supplied query to the owner's forks
+func searchQuery(query, owner string) string {
+	return fmt.Sprintf("%s user:%s fork:only", query, owner)
+}
+
+func searchForkedReposPage(
+	ctx context.Context,
+	baseURL,
+	owner,
+	token,
+	query string,
+	pageNum,
+	perPage int) (searchResult, error) {
+
+	var result searchResult
+
+	searchURL := fmt.Sprintf(
+		"%s/search/repositories?q=%s&page=%d&per_page=%d",
+		baseURL,
+		url.QueryEscape(searchQuery(query, owner)),
+		pageNum,
+		perPage)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return result, err
+	}
+
+	if err := doRequest(req, token, &result); err != nil {
+		// Search has its own, much lower, rate limit that surfaces as a 403 or 429
+		switch err.Error() {
+		case ErrMsg403, fmt.Sprintf("API request failed with status: %d", http.StatusTooManyRequests):
+			return result, fmt.Errorf("search rate limit exceeded, try again in a minute (%w)", err)
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// incompleteSearchError is returned along with the repos of a search that didn't
+// list every match: the search timed out, or there are more matches than the
+// search API returns
+type incompleteSearchError struct {
+	timedOut bool
+	total    int // the number of matches reported by the search API
+}
+
+func (e *incompleteSearchError) Error() string {
+	if e.timedOut {
+		return "incomplete search, the search API timed out before finding every match"
+	}
+	return fmt.Sprintf("incomplete search, only the first %d of %d matches are listed", maxSearchResults, e.total)
+}
+
+// searchForkedRepos enumerates the owner's forks matching a repository search query.
+// Pagination stops at the search API's 1000 result cap. A search that timed out
+// or hit the cap returns the forks it found along with an incompleteSearchError.
+func searchForkedRepos(
+	ctx context.Context,
+	baseURL,
+	owner,
+	token,
+	query string,
+	perPage,
+	maxPage int) ([]Repo, error) {
+
+	var allRepos []Repo
+	var incomplete *incompleteSearchError
+	for pageNum := 1; pageNum <= maxPage && (pageNum-1)*perPage < maxSearchResults; pageNum++ {
+		result, err := searchForkedReposPage(
+			ctx,     // ctx
+			baseURL, // baseURL
+			owner,   // owner
+			token,   // token
+			query,   // query
+			pageNum, // pageNum
+			perPage, // perPage
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range result.Items {
+			if r.IsFork {
+				allRepos = append(allRepos, r)
+			}
+		}
+
+		if result.IncompleteResults {
+			incomplete = &incompleteSearchError{timedOut: true}
+		}
+		if len(result.Items) == 0 || pageNum*perPage >= result.TotalCount {
+			break
+		}
+		if pageNum*perPage >= maxSearchResults && incomplete == nil {
+			incomplete = &incompleteSearchError{total: result.TotalCount}
+		}
+	}
+	if incomplete != nil {
+		return allRepos, incomplete
+	}
+	return allRepos, nil
+}
@@ -0,0 +1,188 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSearchQuery(t *testing.T) {
+	t.Parallel()
+	got := searchQuery("topic:go", "test-owner")
+	expected := "topic:go user:test-owner fork:only"
+	if got != expected {
+		t.Errorf("searchQuery() = %q, want %q", got, expected)
+	}
+}
+
+func TestSearchForkedRepos(t *testing.T) {
+	t.Parallel()
+	var queries []string
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/search/repositories" {
+				t.Errorf("Expected search path, got %s", r.URL.Path)
+			}
+			queries = append(queries, r.URL.Query().Get("q"))
+
+			// Serve 3 results across pages of 2
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			result := searchResult{TotalCount: 3}
+			for i := (page-1)*2 + 1; i <= min(page*2, 3); i++ {
+				result.Items = append(
+					result.Items, Repo{Name: fmt.Sprintf("test-repo-%d", i), IsFork: true})
+			}
+			json.NewEncoder(w).Encode(result)
+		}))
+	defer server.Close()
+
+	forkedRepos, err := searchForkedRepos(
+		context.Background(), // ctx
+		server.URL,           // baseURL
+		"test-owner",         // owner
+		"test-token",         // token
+		"topic:go",           // query
+		2,                    // perPage
+		10,                   // maxPage
+	)
+	if err != nil {
+		t.Fatalf("searchForkedRepos returned an error: %v", err)
+	}
+
+	if len(forkedRepos) != 3 {
+		t.Errorf("Expected 3 forked repos, got %d", len(forkedRepos))
+	}
+	if len(queries) != 2 {
+		t.Errorf("Expected 2 page requests, got %d", len(queries))
+	}
+	if queries[0] != "topic:go user:test-owner fork:only" {
+		t.Errorf("Unexpected search query %q", queries[0])
+	}
+}
+
+func TestSearchForkedRepos_ResultCap(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			result := searchResult{TotalCount: 5000}
+			for i := 0; i < 100; i++ {
+				result.Items = append(result.Items, Repo{Name: "test-repo", IsFork: true})
+			}
+			json.NewEncoder(w).Encode(result)
+		}))
+	defer server.Close()
+
+	// The forks past the cap can't be listed, so the search is incomplete
+	forkedRepos, err := searchForkedRepos(
+		context.Background(), server.URL, "test-owner", "test-token", "", 100, 100)
+	var incomplete *incompleteSearchError
+	if !errors.As(err, &incomplete) || incomplete.timedOut || incomplete.total != 5000 {
+		t.Fatalf("Expected an incomplete search of 5000 matches, got %v", err)
+	}
+
+	if requests != 10 || len(forkedRepos) != maxSearchResults {
+		t.Errorf(
+			"Expected 10 requests and %d repos, got %d and %d",
+			maxSearchResults,
+			requests,
+			len(forkedRepos))
+	}
+}
+
+func TestSearchForkedRepos_TimedOut(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(searchResult{
+				TotalCount:        1,
+				IncompleteResults: true,
+				Items:             []Repo{{Name: "test-repo", IsFork: true}},
+			})
+		}))
+	defer server.Close()
+
+	forkedRepos, err := searchForkedRepos(
+		context.Background(), server.URL, "test-owner", "test-token", "", 100, 10)
+	var incomplete *incompleteSearchError
+	if !errors.As(err, &incomplete) || !incomplete.timedOut {
+		t.Fatalf("Expected a timed out search, got %v", err)
+	}
+	if len(forkedRepos) != 1 {
+		t.Errorf("Expected the forks found before the timeout, got %v", forkedRepos)
+	}
+}
+
+func TestCLI_SearchIncomplete(t *testing.T) {
+	t.Parallel()
+	run := func(args ...string) (int, string, int) {
+		stderr := new(bytes.Buffer)
+		deletes := 0
+		cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+			withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+			withSearchForkedRepos(func(
+				ctx context.Context,
+				baseURL,
+				owner,
+				token,
+				query string,
+				perPage,
+				maxPage int) ([]Repo, error) {
+				return []Repo{newTestRepo("repo-1")}, &incompleteSearchError{timedOut: true}
+			}).
+			withFilterForkedRepos(mockFilterForkedRepos).
+			withDeleteRepos(func(
+				ctx context.Context,
+				baseURL,
+				token string,
+				repos []Repo) (deleteResult, error) {
+				deletes += len(repos)
+				return deleteResult{succeeded: repos}, nil
+			}).
+			withFlagErrorHandling(mockFlagErrorHandler)
+
+		args = append([]string{
+			"--owner", "testOwner",
+			"--token", "testToken",
+			"--repos-from-search", "topic:go",
+		}, args...)
+		return cliConfig.CLI(args), stderr.String(), deletes
+	}
+
+	// Listing warns about the matches that weren't searched
+	exitCode, errOut, _ := run()
+	if exitCode != 0 || !strings.Contains(errOut, "Warning: incomplete search, the search API timed out") {
+		t.Errorf("Expected a warning, got %d: %q", exitCode, errOut)
+	}
+
+	// Deleting from an incomplete search takes --allow-incomplete
+	if exitCode, errOut, deletes := run("--delete"); exitCode != exitPartial || deletes != 0 {
+		t.Errorf("Expected exit code %d and no deletions, got %d and %d: %q", exitPartial, exitCode, deletes, errOut)
+	}
+	if exitCode, errOut, deletes := run("--delete", "--allow-incomplete"); exitCode != 0 || deletes != 1 {
+		t.Errorf("Expected exit code 0 and 1 deletion, got %d and %d: %q", exitCode, deletes, errOut)
+	}
+}
+
+func TestSearchForkedRepos_RateLimited(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+	defer server.Close()
+
+	_, err := searchForkedRepos(
+		context.Background(), server.URL, "test-owner", "test-token", "", 100, 1)
+	if err == nil || !strings.Contains(err.Error(), "search rate limit exceeded") {
+		t.Errorf("Expected search rate limit error, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package src
+
+import (
+	"hash/fnv"
+	"math"
+	"net"
+	"net/url"
+)
+
+// simulatedFailure reports whether the delete of the repo identified by key
+// should fail when simulating failures at the given rate. The choice hashes the
+// key so that the same repos fail on every run.
+func simulatedFailure(rate float64, key string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return float64(h.Sum32())/(math.MaxUint32+1) < rate
+}
+
+// isLocalURL reports whether the base URL points at the local machine. Failure
+// simulation is only allowed there so that it can never affect a real account.
+func isLocalURL(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
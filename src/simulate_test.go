@@ -0,0 +1,109 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulatedFailure(t *testing.T) {
+	t.Parallel()
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("test-owner/repo-%d", i)
+		if simulatedFailure(0, key) {
+			t.Fatalf("Expected no failures at rate 0, %s failed", key)
+		}
+		if !simulatedFailure(1, key) {
+			t.Fatalf("Expected every delete to fail at rate 1, %s didn't", key)
+		}
+		if simulatedFailure(0.5, key) != simulatedFailure(0.5, key) {
+			t.Fatalf("Expected %s to fail deterministically", key)
+		}
+		if simulatedFailure(0.5, key) {
+			failed++
+		}
+	}
+
+	if failed < 400 || failed > 600 {
+		t.Errorf("Expected about half of the deletes to fail at rate 0.5, got %d", failed)
+	}
+}
+
+func TestIsLocalURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"http://127.0.0.1:8080", true},
+		{"http://localhost:3000/api/v3", true},
+		{"http://[::1]:8080", true},
+		{defaultBaseURL, false},
+		{"https://ghe.example.com/api/v3", false},
+		{"://bad", false},
+	}
+
+	for _, tt := range tests {
+		if got := isLocalURL(tt.url); got != tt.expected {
+			t.Errorf("isLocalURL(%q) = %v, want %v", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestCLI_SimulateFailureRate(t *testing.T) {
+	t.Parallel()
+	server, deleted := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", time.Now().AddDate(-1, 0, 0))})
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		rate     string
+		wantCode int
+		wantErr  string
+	}{
+		{
+			"local server",
+			server.URL,
+			"1",
+			exitPartial,
+			"simulated failure deleting testOwner/stale-repo",
+		},
+		{"production API", defaultBaseURL, "1", exitErr, "can only be used with a local api-url"},
+		{"out of range", server.URL, "1.5", exitErr, "must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(tt.baseURL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{
+				"--owner", "testOwner",
+				"--token", "testToken",
+				"--delete",
+				"--simulate-failure-rate", tt.rate,
+			}
+			if exitCode := cliConfig.CLI(args); exitCode != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, exitCode)
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, stderr.String())
+			}
+		})
+	}
+
+	if len(*deleted) != 0 {
+		t.Errorf("Expected no deletes to reach the server, got %v", *deleted)
+	}
+}
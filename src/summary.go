@@ -0,0 +1,60 @@
+package src
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// summary is the aggregate outcome of a run written by --summary-json-to,
+// whatever the output format
+type summary struct {
+	Owner           string    `json:"owner"`
+	GeneratedAt     time.Time `json:"generated_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Found           int       `json:"found"`
+	Guarded         int       `json:"guarded"`
+	Unguarded       int       `json:"unguarded"`
+	Deleted         int       `json:"deleted"`
+	Failed          int       `json:"failed"`
+
+	// RateLimit is left out when the API didn't send rate limit headers
+	RateLimit *rateLimitUsage `json:"rate_limit,omitempty"`
+
+	// Endpoint is the path forks were listed from and EndpointReason why it was
+	// picked, which tells whether private forks could be listed
+	Endpoint       string `json:"endpoint,omitempty"`
+	EndpointReason string `json:"endpoint_reason,omitempty"`
+
+	// Aborted says why the run refused to delete, e.g. over --max-delete
+	Aborted string `json:"aborted,omitempty"`
+}
+
+func newSummary(
+	owner string,
+	start,
+	generatedAt time.Time,
+	guardedRepos,
+	unguardedRepos,
+	deletedRepos []Repo,
+	failed int) summary {
+
+	return summary{
+		Owner:           owner,
+		GeneratedAt:     generatedAt,
+		DurationSeconds: generatedAt.Sub(start).Round(time.Millisecond).Seconds(),
+		Found:           len(guardedRepos) + len(unguardedRepos),
+		Guarded:         len(guardedRepos),
+		Unguarded:       len(unguardedRepos),
+		Deleted:         len(deletedRepos),
+		Failed:          failed,
+	}
+}
+
+func writeSummary(path string, s summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
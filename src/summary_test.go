@@ -0,0 +1,156 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSummary(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guarded := []Repo{newTestRepo("repo-1")}
+	unguarded := []Repo{newTestRepo("repo-2"), newTestRepo("repo-3")}
+
+	got := newSummary(
+		"test-owner", start, start.Add(1500*time.Millisecond), guarded, unguarded, unguarded[:1], 1)
+	expected := summary{
+		Owner:           "test-owner",
+		GeneratedAt:     start.Add(1500 * time.Millisecond),
+		DurationSeconds: 1.5,
+		Found:           3,
+		Guarded:         1,
+		Unguarded:       2,
+		Deleted:         1,
+		Failed:          1,
+	}
+	if got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestCLI_SummaryJSONTo(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, _ := newMockGitHubServer(
+		t, []Repo{newMockFork("stale-repo", old), newMockFork("active-repo", time.Now())})
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--delete", "--summary-json-to", path,
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the summary to be written: %v", err)
+	}
+	var got summary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid summary %q: %v", data, err)
+	}
+	if got.Owner != "testOwner" || got.Found != 2 || got.Guarded != 1 || got.Deleted != 1 {
+		t.Errorf("Unexpected summary %+v", got)
+	}
+	if bytes.Contains(stdout.Bytes(), []byte(`"found"`)) {
+		t.Errorf("Expected the text output to be unaffected, got %q", stdout)
+	}
+}
+
+func TestCLI_SummaryJSONToEmpty(t *testing.T) {
+	t.Parallel()
+	server, _ := newMockGitHubServer(t, nil)
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(
+		new(bytes.Buffer),
+		stderr,
+		"test-version",
+	).withBaseURL(server.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	// An empty sweep is recorded like any other
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--summary-json-to", path}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the summary to be written: %v", err)
+	}
+	var got summary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid summary %q: %v", data, err)
+	}
+	if got.Owner != "testOwner" || got.Found != 0 || got.Guarded != 0 || got.Unguarded != 0 || got.Deleted != 0 {
+		t.Errorf("Expected an empty summary, got %+v", got)
+	}
+}
+
+func TestCLI_SummaryEndpoint(t *testing.T) {
+	t.Parallel()
+	server, _ := newMockGitHubServer(t, []Repo{newMockFork("repo-1", time.Now())})
+
+	tests := []struct {
+		owner      string
+		wantPath   string
+		wantReason string
+	}{
+		{"testOwner", "/user/repos", "the token belongs to the owner, private forks included"},
+		{"otherOwner", "/users/otherOwner/repos", "the token doesn't belong to otherOwner, public forks only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.owner, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "summary.json")
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withBaseURL(server.URL).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := []string{
+				"--owner", tt.owner, "--token", "testToken", "--verbose", "--summary-json-to", path,
+			}
+			if exitCode := cliConfig.CLI(args); exitCode != 0 {
+				t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+			}
+
+			want := "Listing forks from " + tt.wantPath + ": " + tt.wantReason
+			if !bytes.Contains(stdout.Bytes(), []byte(want)) {
+				t.Errorf("Expected %q in the output, got %q", want, stdout)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Expected the summary to be written: %v", err)
+			}
+			var got summary
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Invalid summary %q: %v", data, err)
+			}
+			if got.Endpoint != tt.wantPath || got.EndpointReason != tt.wantReason {
+				t.Errorf("Expected endpoint %s (%s), got %s (%s)",
+					tt.wantPath, tt.wantReason, got.Endpoint, got.EndpointReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package src
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const ghDefaultHost = "github.com"
+
+// runTokenCommand runs the command through the shell and returns its trimmed
+// stdout as the token, e.g. for `gh auth token` or a secrets manager's CLI
+func runTokenCommand(ctx context.Context, command string) (string, error) {
+	out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf(
+				"token command failed with %s: %s",
+				exitErr.ProcessState,
+				strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token command printed no token")
+	}
+	return token, nil
+}
+
+// ghHostsPath returns the location of the gh CLI's hosts.yml, honoring the
+// same environment variables as gh itself
+func ghHostsPath(lookupEnv func(key string) (string, bool)) string {
+	if dir, ok := lookupEnv("GH_CONFIG_DIR"); ok && dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir, ok := lookupEnv("XDG_CONFIG_HOME"); ok && dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	home, _ := lookupEnv("HOME")
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// readGHHosts maps each host in gh's hosts.yml to its oauth_token. A host's
+// own token wins over the per-user tokens nested under it. Hosts whose token
+// lives in the system keyring map to an empty string
+func readGHHosts(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("gh config not found at %s, run 'gh auth login' first", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hosts := map[string]string{}
+	var host string
+	childIndent := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := len(line) - len(trimmed)
+		if indent == 0 {
+			host = strings.Trim(strings.TrimSuffix(trimmed, ":"), `"'`)
+			hosts[host] = ""
+			childIndent = 0
+			continue
+		}
+		if host == "" {
+			continue
+		}
+		if childIndent == 0 {
+			childIndent = indent
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok || key != "oauth_token" {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if indent == childIndent || hosts[host] == "" {
+			hosts[host] = value
+		}
+	}
+	return hosts, scanner.Err()
+}
+
+// ghHostAPIURL returns the REST API base URL of a gh host
+func ghHostAPIURL(host string) string {
+	if host == ghDefaultHost {
+		return defaultBaseURL
+	}
+	return fmt.Sprintf("https://%s/api/v3", host)
+}
+
+// ghHostFromAPIURL returns the gh host an API base URL belongs to
+func ghHostFromAPIURL(baseURL string) string {
+	if strings.TrimSuffix(baseURL, "/") == defaultBaseURL {
+		return ghDefaultHost
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return baseURL
+	}
+	return u.Host
+}
+
+// ghToken looks up the host's token in gh's hosts.yml
+func ghToken(path, host string) (string, error) {
+	hosts, err := readGHHosts(path)
+	if err != nil {
+		return "", err
+	}
+	token, ok := hosts[host]
+	if !ok {
+		return "", fmt.Errorf("host '%s' not found in gh config at %s", host, path)
+	}
+	if token == "" {
+		return "", fmt.Errorf(
+			"no token for host '%s' in %s, gh may keep it in the system keyring; "+
+				"try --token-command 'gh auth token'", host, path)
+	}
+	return token, nil
+}
@@ -0,0 +1,310 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTokenCommand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		command   string
+		wantToken string
+		wantErr   string
+	}{
+		{"prints token", "echo '  test-token  '", "test-token", ""},
+		{"fails", "echo 'vault sealed' >&2; exit 3", "", "exit status 3: vault sealed"},
+		{"prints nothing", "true", "", "printed no token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := runTokenCommand(context.Background(), tt.command)
+			if token != tt.wantToken {
+				t.Errorf("Expected token %q, got %q", tt.wantToken, token)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("runTokenCommand() failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCLI_TokenCommand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantToken    string
+	}{
+		{"token from command", []string{"--token-command", "echo cmdToken"}, 0, "cmdToken"},
+		{
+			"token flag wins",
+			[]string{"--token", "flagToken", "--token-command", "echo cmdToken"},
+			0,
+			"flagToken",
+		},
+		{"failing command", []string{"--token-command", "exit 1"}, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			var gotToken string
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+				withFetchForkedRepos(func(
+					ctx context.Context,
+					baseURL,
+					owner,
+					token,
+					endpoint string,
+					perPage,
+					maxPage int) ([]Repo, error) {
+					gotToken = token
+					return nil, nil
+				}).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			exitCode := cliConfig.CLI(append([]string{"--owner", "testOwner"}, tt.args...))
+
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantExitCode, exitCode)
+			}
+			if gotToken != tt.wantToken {
+				t.Errorf("Expected token %q, got %q", tt.wantToken, gotToken)
+			}
+		})
+	}
+}
+
+const testGHHosts = `github.com:
+    users:
+        testOwner:
+            oauth_token: userToken
+    git_protocol: https
+    oauth_token: hostToken
+    user: testOwner
+ghe.example.com:
+    users:
+        testOwner:
+            oauth_token: "gheToken"
+    user: testOwner
+keyring.example.com:
+    user: testOwner
+`
+
+func TestReadGHHosts(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "hosts.yml")
+	if err := os.WriteFile(path, []byte(testGHHosts), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := readGHHosts(path)
+	if err != nil {
+		t.Fatalf("readGHHosts() failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"github.com":          "hostToken",
+		"ghe.example.com":     "gheToken",
+		"keyring.example.com": "",
+	}
+	if len(hosts) != len(expected) {
+		t.Fatalf("Expected %d hosts, got %v", len(expected), hosts)
+	}
+	for host, token := range expected {
+		if hosts[host] != token {
+			t.Errorf("Expected token %q for %s, got %q", token, host, hosts[host])
+		}
+	}
+
+	if _, err := readGHHosts(filepath.Join(t.TempDir(), "hosts.yml")); err == nil ||
+		!strings.Contains(err.Error(), "gh auth login") {
+		t.Errorf("Expected a missing config error, got %v", err)
+	}
+}
+
+func TestGHToken(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "hosts.yml")
+	if err := os.WriteFile(path, []byte(testGHHosts), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host      string
+		wantToken string
+		wantErr   string
+	}{
+		{"github.com", "hostToken", ""},
+		{"ghe.example.com", "gheToken", ""},
+		{"keyring.example.com", "", "system keyring"},
+		{"missing.example.com", "", "not found in gh config"},
+	}
+
+	for _, tt := range tests {
+		token, err := ghToken(path, tt.host)
+		if token != tt.wantToken {
+			t.Errorf("ghToken(%q) = %q, want %q", tt.host, token, tt.wantToken)
+		}
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("ghToken(%q) failed: %v", tt.host, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+		}
+	}
+}
+
+func TestGHHostsPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{"HOME": "/home/test"}, "/home/test/.config/gh/hosts.yml"},
+		{
+			map[string]string{"HOME": "/home/test", "XDG_CONFIG_HOME": "/xdg"},
+			"/xdg/gh/hosts.yml",
+		},
+		{
+			map[string]string{"XDG_CONFIG_HOME": "/xdg", "GH_CONFIG_DIR": "/gh"},
+			"/gh/hosts.yml",
+		},
+	}
+
+	for _, tt := range tests {
+		lookupEnv := func(key string) (string, bool) {
+			v, ok := tt.env[key]
+			return v, ok
+		}
+		if got := ghHostsPath(lookupEnv); got != tt.expected {
+			t.Errorf("ghHostsPath(%v) = %q, want %q", tt.env, got, tt.expected)
+		}
+	}
+}
+
+func TestGHHostAPIURL(t *testing.T) {
+	t.Parallel()
+	if got := ghHostAPIURL("github.com"); got != defaultBaseURL {
+		t.Errorf("Expected %q, got %q", defaultBaseURL, got)
+	}
+	if got := ghHostAPIURL("ghe.example.com"); got != "https://ghe.example.com/api/v3" {
+		t.Errorf("Unexpected API URL %q", got)
+	}
+	if got := ghHostFromAPIURL(defaultBaseURL + "/"); got != "github.com" {
+		t.Errorf("Expected github.com, got %q", got)
+	}
+	if got := ghHostFromAPIURL("https://ghe.example.com/api/v3"); got != "ghe.example.com" {
+		t.Errorf("Expected ghe.example.com, got %q", got)
+	}
+}
+
+func TestCLI_UseGHAuth(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		wantExitCode int
+		wantToken    string
+		wantBaseURL  string
+	}{
+		{"token from gh", nil, nil, 0, "hostToken", defaultBaseURL},
+		{"token flag wins", []string{"--token", "flagToken"}, nil, 0, "flagToken", defaultBaseURL},
+		{
+			"host from GH_HOST",
+			nil,
+			map[string]string{"GH_HOST": "ghe.example.com"},
+			0,
+			"gheToken",
+			"https://ghe.example.com/api/v3",
+		},
+		{
+			"host from api-url",
+			[]string{"--api-url", "https://ghe.example.com/api/v3"},
+			nil,
+			0,
+			"gheToken",
+			"https://ghe.example.com/api/v3",
+		},
+		{
+			"host missing",
+			[]string{"--api-url", "https://missing.example.com/api/v3"},
+			nil,
+			1,
+			"",
+			"",
+		},
+		{"config missing", nil, map[string]string{"GH_CONFIG_DIR": "/nonexistent"}, 1, "", ""},
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(testGHHosts), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			var gotToken, gotBaseURL string
+
+			env := map[string]string{"GH_CONFIG_DIR": dir}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+
+			cliConfig := NewCLIConfig(
+				stdout,
+				stderr,
+				"test-version",
+			).withLookupEnv(func(key string) (string, bool) {
+				v, ok := env[key]
+				return v, ok
+			}).
+				withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+				withFetchForkedRepos(func(
+					ctx context.Context,
+					baseURL,
+					owner,
+					token,
+					endpoint string,
+					perPage,
+					maxPage int) ([]Repo, error) {
+					gotToken, gotBaseURL = token, baseURL
+					return nil, nil
+				}).
+				withFlagErrorHandling(mockFlagErrorHandler)
+
+			args := append([]string{"--owner", "testOwner", "--use-gh-auth"}, tt.args...)
+			exitCode := cliConfig.CLI(args)
+
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.wantExitCode, exitCode, stderr)
+			}
+			if gotToken != tt.wantToken {
+				t.Errorf("Expected token %q, got %q", tt.wantToken, gotToken)
+			}
+			if gotBaseURL != tt.wantBaseURL {
+				t.Errorf("Expected base URL %q, got %q", tt.wantBaseURL, gotBaseURL)
+			}
+		})
+	}
+}
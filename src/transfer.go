@@ -0,0 +1,75 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// transferRepo asks GitHub to transfer the repo to another owner. Transfers are
+// asynchronous, GitHub accepts the request with a 202 and moves the repo later.
+func transferRepo(ctx context.Context, baseURL, owner, name, newOwner, token string) error {
+	url := repoURL(baseURL, owner, name) + "/transfer"
+
+	if err := requestConfigFrom(ctx).deleteLimiter.wait(ctx); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]string{"new_owner": newOwner})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	return doRequest(req, token, nil)
+}
+
+// transferRepos transfers the repos concurrently and returns the ones whose
+// transfer was initiated, in their original order, along with the first error
+func transferRepos(
+	ctx context.Context,
+	baseURL,
+	token,
+	newOwner string,
+	repos []Repo) ([]Repo, error) {
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	initiated := make([]bool, len(repos))
+
+	for i, r := range repos {
+		wg.Add(1)
+		go func(i int, r Repo) {
+			defer wg.Done()
+			err := transferRepo(ctx, baseURL, r.Owner.Name, r.Name, newOwner, token)
+			if err != nil {
+				select {
+				case errChan <- err:
+				default:
+				}
+				return
+			}
+			initiated[i] = true
+		}(i, r)
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	var transferred []Repo
+	for i, r := range repos {
+		if initiated[i] {
+			transferred = append(transferred, r)
+		}
+	}
+
+	if len(errChan) > 0 {
+		return transferred, <-errChan
+	}
+	return transferred, nil
+}
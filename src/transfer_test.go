@@ -0,0 +1,129 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransferRepos(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			NewOwner string `json:"new_owner"`
+		}
+		if r.Method != "POST" ||
+			json.NewDecoder(r.Body).Decode(&body) != nil ||
+			body.NewOwner != "archive-org" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/repos/test-owner/repo-1/transfer", "/repos/test-owner/repo-3/transfer":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"name": "repo"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	repos := []Repo{newTestRepo("repo-1"), newTestRepo("repo-2"), newTestRepo("repo-3")}
+	transferred, err := transferRepos(
+		context.Background(), server.URL, "test-token", "archive-org", repos)
+
+	if err == nil || err.Error() != ErrMsg403 {
+		t.Errorf("Expected a 403 error, got %v", err)
+	}
+
+	var got []string
+	for _, r := range transferred {
+		got = append(got, r.Name)
+	}
+	if expected := []string{"repo-1", "repo-3"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected initiated transfers %v, got %v", expected, got)
+	}
+}
+
+func TestCLI_TransferTo(t *testing.T) {
+	t.Parallel()
+	old := time.Now().AddDate(-1, 0, 0)
+	server, deleted := newMockGitHubServer(t, []Repo{
+		newMockFork("stale-repo", old),
+		newMockFork("active-repo", time.Now()),
+	})
+
+	// Accept transfers next to the mock GitHub API
+	var mu sync.Mutex
+	var transfers []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/transfer") {
+			mu.Lock()
+			transfers = append(transfers, r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer api.Close()
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withBaseURL(api.URL).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--transfer-to", "archive-org"}
+	if exitCode := cliConfig.CLI(args); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if !reflect.DeepEqual(transfers, []string{"/repos/testOwner/stale-repo/transfer"}) {
+		t.Errorf("Expected only stale-repo to be transferred, got %v", transfers)
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("Expected nothing to be deleted, got %v", *deleted)
+	}
+	want := "Initiated 1 of 1 transfers:\n" +
+		"    - https://github.com/testOwner/stale-repo -> archive-org/stale-repo\n"
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("Expected %q in output, got %q", want, stdout.String())
+	}
+}
+
+func TestCLI_TransferToWithDelete(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{
+		"--owner", "testOwner", "--token", "testToken", "--transfer-to", "archive-org", "--delete",
+	}
+	if exitCode := cliConfig.CLI(args); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "mutually exclusive") {
+		t.Errorf("Expected a mutually exclusive error, got %q", stderr.String())
+	}
+}
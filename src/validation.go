@@ -0,0 +1,83 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Longest 422 response body read for its validation details
+const maxValidationBodyBytes = 64 << 10
+
+// validationError is a 422 response, which GitHub sends for requests it
+// understood but refused, like transferring a repo to an owner that already has
+// one by that name. Its message and details say what was wrong.
+type validationError struct {
+	message string
+	details []string
+}
+
+func (e *validationError) Error() string {
+	msg := fmt.Sprintf("API request failed with status: %d", http.StatusUnprocessableEntity)
+	if e.message != "" {
+		msg += ": " + e.message
+	}
+	if len(e.details) > 0 {
+		msg += " (" + strings.Join(e.details, "; ") + ")"
+	}
+	return msg
+}
+
+// readValidationError builds a validationError from the body of a 422 response,
+// leaving the body intact for the error log. A body that isn't GitHub's error
+// JSON leaves the error without details.
+func readValidationError(resp *http.Response) *validationError {
+	body, err := peekBody(resp, maxValidationBodyBytes)
+	if err != nil {
+		return &validationError{}
+	}
+
+	var payload struct {
+		Message string            `json:"message"`
+		Errors  []json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return &validationError{}
+	}
+
+	verr := &validationError{message: payload.Message}
+	for _, raw := range payload.Errors {
+		if detail := formatValidationDetail(raw); detail != "" {
+			verr.details = append(verr.details, detail)
+		}
+	}
+	return verr
+}
+
+// formatValidationDetail describes an entry of a 422's errors, which is either a
+// plain string or an object naming the field and what was wrong with it
+func formatValidationDetail(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var detail struct {
+		Resource string `json:"resource"`
+		Field    string `json:"field"`
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &detail); err != nil {
+		return ""
+	}
+	switch {
+	case detail.Message != "":
+		return detail.Message
+	case detail.Field != "" && detail.Code != "":
+		return fmt.Sprintf("%s %s", detail.Field, strings.ReplaceAll(detail.Code, "_", " "))
+	default:
+		return detail.Code
+	}
+}
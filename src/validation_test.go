@@ -0,0 +1,66 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDoRequest_ValidationError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			"object details",
+			`{"message": "Validation Failed", "errors": [` +
+				`{"resource": "Repository", "field": "name", "code": "already_exists"},` +
+				`{"resource": "Repository", "code": "custom", "message": "You don't have the permission to create public repositories on archive-org"}]}`,
+			"API request failed with status: 422: Validation Failed " +
+				"(name already exists; You don't have the permission to create public repositories on archive-org)",
+		},
+		{
+			"string details",
+			`{"message": "Repository cannot be transferred", "errors": ["new_owner is invalid"]}`,
+			"API request failed with status: 422: Repository cannot be transferred (new_owner is invalid)",
+		},
+		{
+			"no details",
+			`{"message": "Validation Failed"}`,
+			"API request failed with status: 422: Validation Failed",
+		},
+		{
+			"not json",
+			`unprocessable`,
+			"API request failed with status: 422",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					fmt.Fprint(w, tt.body)
+				}))
+			defer server.Close()
+
+			req, _ := http.NewRequestWithContext(
+				context.Background(), "POST", server.URL+"/repos/o/r/transfer", nil)
+			err := doRequest(req, "test-token", nil)
+
+			var verr *validationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected a validation error, got %v", err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
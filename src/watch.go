@@ -0,0 +1,48 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// watch sweeps every interval until interrupted, each sweep being a full run of
+// the CLI with the same args. A rejected token stops the watch since every
+// later sweep would fail the same way.
+func (c *cliConfig) watch(args []string, interval time.Duration) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sweep := *c
+	sweep.watching = true
+
+	for run := 1; ; run++ {
+		start := time.Now()
+		code := sweep.CLI(args)
+		fmt.Fprintf(
+			c.stderr,
+			"\nSweep %d finished in %s with exit code %d\n",
+			run,
+			time.Since(start).Round(100*time.Millisecond),
+			code)
+
+		switch {
+		case code == exitAuth:
+			fmt.Fprintln(c.stderr, "Error: stopping the watch, the token was rejected")
+			return code
+		case code == exitInterrupted:
+			return code
+		case ctx.Err() != nil:
+			return exitOk
+		}
+
+		next := start.Add(interval)
+		fmt.Fprintf(c.stderr, "Next sweep at %s\n", next.Format(time.RFC3339))
+		if err := sleepContext(ctx, time.Until(next)); err != nil {
+			return exitOk
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCLI_Watch(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	// Rejecting the token on the third sweep, which ends the watch
+	var sweeps atomic.Int64
+	cliConfig := NewCLIConfig(
+		stdout,
+		stderr,
+		"test-version",
+	).withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(func(
+			ctx context.Context,
+			baseURL,
+			owner,
+			token,
+			endpoint string,
+			perPage,
+			maxPage int) ([]Repo, error) {
+			if sweeps.Add(1) == 3 {
+				return nil, errors.New(ErrMsg401)
+			}
+			return mockFetchForkedRepos(ctx, baseURL, owner, token, endpoint, perPage, maxPage)
+		}).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--watch", "1ms"}
+	if exitCode := cliConfig.CLI(args); exitCode != exitAuth {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitAuth, exitCode, stderr.String())
+	}
+
+	if sweeps.Load() != 3 {
+		t.Errorf("Expected 3 sweeps, got %d", sweeps.Load())
+	}
+	for _, want := range []string{
+		"Sweep 2 finished in",
+		"Sweep 3 finished in",
+		"stopping the watch, the token was rejected",
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in the output, got %q", want, stderr.String())
+		}
+	}
+}
+
+func TestCLI_WatchExclusive(t *testing.T) {
+	t.Parallel()
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--watch", "1h", "--health-check"}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d", exitErr, exitCode)
+	}
+	if !strings.Contains(stderr.String(), "watch can't be combined with") {
+		t.Errorf("Expected error message not found in output: %q", stderr.String())
+	}
+}
@@ -0,0 +1,73 @@
+package src
+
+import (
+	"fmt"
+	"time"
+)
+
+const windowDateLayout = "2006-01-02"
+
+// pushWindow selects forks last pushed at or after since and before until. A
+// zero bound leaves that side of the window open.
+type pushWindow struct {
+	since time.Time
+	until time.Time
+}
+
+// parsePushWindow reads --pushed-since and --pushed-until, each a date or an
+// RFC 3339 timestamp. A date until includes the whole day, so 2021-01-01 to
+// 2021-03-31 covers the first quarter.
+func parsePushWindow(since, until string) (pushWindow, error) {
+	var w pushWindow
+	var err error
+	if since != "" {
+		if w.since, err = parseWindowBound(since, false); err != nil {
+			return pushWindow{}, fmt.Errorf("invalid pushed-since '%s': %w", since, err)
+		}
+	}
+	if until != "" {
+		if w.until, err = parseWindowBound(until, true); err != nil {
+			return pushWindow{}, fmt.Errorf("invalid pushed-until '%s': %w", until, err)
+		}
+	}
+	if !w.since.IsZero() && !w.until.IsZero() && !w.since.Before(w.until) {
+		return pushWindow{}, fmt.Errorf("pushed-since %s must be before pushed-until %s", since, until)
+	}
+	return w, nil
+}
+
+func parseWindowBound(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(windowDateLayout, s); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date like 2021-01-31 or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+func (w pushWindow) isSet() bool {
+	return !w.since.IsZero() || !w.until.IsZero()
+}
+
+// contains reports whether pushedAt falls in the window. A fork that was never
+// pushed to is outside any window.
+func (w pushWindow) contains(pushedAt time.Time) bool {
+	if pushedAt.IsZero() {
+		return false
+	}
+	return (w.since.IsZero() || !pushedAt.Before(w.since)) &&
+		(w.until.IsZero() || pushedAt.Before(w.until))
+}
+
+// formatOutsideWindow describes why a fork's last push keeps it out of the window
+func formatOutsideWindow(pushedAt time.Time) string {
+	if pushedAt.IsZero() {
+		return "never pushed, outside the pushed window"
+	}
+	return fmt.Sprintf("last pushed %s, outside the pushed window", pushedAt.UTC().Format(windowDateLayout))
+}
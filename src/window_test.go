@@ -0,0 +1,107 @@
+package src
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePushWindow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		since   string
+		until   string
+		want    pushWindow
+		wantErr string
+	}{
+		{"unset", "", "", pushWindow{}, ""},
+		{
+			"dates",
+			"2021-01-01",
+			"2021-03-31",
+			pushWindow{
+				since: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+				until: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			},
+			"",
+		},
+		{
+			"timestamp",
+			"",
+			"2021-03-31T12:00:00Z",
+			pushWindow{until: time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC)},
+			"",
+		},
+		{"invalid", "Jan 2021", "", pushWindow{}, "invalid pushed-since 'Jan 2021'"},
+		{"reversed", "2021-03-31", "2021-01-01", pushWindow{}, "must be before pushed-until"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePushWindow(tt.since, tt.until)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePushWindow() failed: %v", err)
+			}
+			if !got.since.Equal(tt.want.since) || !got.until.Equal(tt.want.until) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterForkedRepos_PushedWindow(t *testing.T) {
+	t.Parallel()
+	window, err := parsePushWindow("2021-01-01", "2021-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forkedRepos := []Repo{
+		{Name: "before-repo", PushedAt: time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC)},
+		{Name: "first-day-repo", PushedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "last-day-repo", PushedAt: time.Date(2021, 3, 31, 23, 0, 0, 0, time.UTC)},
+		{Name: "after-repo", PushedAt: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "never-pushed-repo"},
+	}
+
+	reasons := map[string]string{}
+	unguarded, _ := filterForkedRepos(forkedRepos, filterOptions{
+		olderThanDays: 30,
+		pushedWindow:  window,
+		onDecision: func(r Repo, guarded bool, reason string) {
+			reasons[r.Name] = reason
+		},
+	})
+
+	if len(unguarded) != 2 || unguarded[0].Name != "first-day-repo" || unguarded[1].Name != "last-day-repo" {
+		t.Errorf("Expected only the repos pushed in the window to be unguarded, got %v", unguarded)
+	}
+	if reasons["before-repo"] != "last pushed 2020-12-31, outside the pushed window" ||
+		reasons["never-pushed-repo"] != "never pushed, outside the pushed window" {
+		t.Errorf("Unexpected reasons %v", reasons)
+	}
+}
+
+func TestCLI_PushedWindowInvalid(t *testing.T) {
+	t.Parallel()
+	stderr := new(bytes.Buffer)
+	cliConfig := NewCLIConfig(new(bytes.Buffer), stderr, "test-version").
+		withFetchAuthenticatedUser(mockFetchAuthenticatedUser).
+		withFetchForkedRepos(mockFetchForkedRepos).
+		withFlagErrorHandling(mockFlagErrorHandler)
+
+	args := []string{"--owner", "testOwner", "--token", "testToken", "--pushed-until", "yesterday"}
+	if exitCode := cliConfig.CLI(args); exitCode != exitErr {
+		t.Errorf("Expected exit code %d, got %d", exitErr, exitCode)
+	}
+	if !strings.Contains(stderr.String(), "Error: invalid pushed-until 'yesterday'") {
+		t.Errorf("Expected error message not found in output: %q", stderr)
+	}
+}